@@ -2,27 +2,350 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/AltMax/art-test/postgresql"
 	"github.com/jackc/pgx"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 )
 
 // Config contains all configurable vars for apps.
 type Config struct {
 	ServerAddr        string            `mapstructure:"server_addr"`
+	AdminAddr         string            `mapstructure:"admin_addr"` //AdminService listens separately, empty disables it
 	Postgresql        postgresql.Config `mapstructure:"postgresql"`
+	Replicas          Replicas          `mapstructure:"replicas"`
 	LRUCacheSize      int               `mapstructure:"lru_cache_size"`
-	FetchUnitsTimeout int64             `mapstructure:"fetch_units_timeout"` //seconds
+	FetchUnitsTimeout time.Duration     `mapstructure:"fetch_units_timeout"` //interval of full syncs
+	DeltaSyncInterval time.Duration     `mapstructure:"delta_sync_interval"` //0 disables delta syncs
+	DeltaSyncOverlap  time.Duration     `mapstructure:"delta_sync_overlap"`
+	MaxRemovedRatio   float64           `mapstructure:"max_removed_ratio"`     //share by which a full sync may shrink before removal is suspended
+	RemovalConfirms   int               `mapstructure:"removal_confirmations"` //agreeing shrunk full syncs after which removal is applied
+	CoalesceLookups   bool              `mapstructure:"coalesce_lookups"`      //concurrent lookups of the same ids share one query
+	Logging           Logging           `mapstructure:"logging"`
+	Deadlines         Deadlines         `mapstructure:"deadlines"`
+	Store             Store             `mapstructure:"store"`
+	Notifications     Notifications     `mapstructure:"notifications"`
+	NegativeCache     NegativeCache     `mapstructure:"negative_cache"`
+	Cache             Cache             `mapstructure:"cache"`
+	Snapshot          Snapshot          `mapstructure:"snapshot"`
+	Redis             Redis             `mapstructure:"redis"`
+	WriteBehind       WriteBehind       `mapstructure:"write_behind"`
+	Metrics           Metrics           `mapstructure:"metrics"`
+	FindByIDs         FindByIDs         `mapstructure:"find_by_ids"`
+	FetchAll          FetchAll          `mapstructure:"fetch_all"`
+	Sync              Sync              `mapstructure:"sync"`
+	Leader            Leader            `mapstructure:"leader"`
+	Tombstones        Tombstones        `mapstructure:"tombstones"`
+	// Layers are names of units layers from the innermost one,
+	// see LayerNames for the default.
+	Layers []string `mapstructure:"layers"`
+}
+
+// LayerNames returns Layers, when they are not set the layers are selected
+// by the older settings: redis by Redis.Addr, coalesce by CoalesceLookups
+// and write_behind by WriteBehind.Enabled.
+func (c Config) LayerNames() []string {
+	if len(c.Layers) > 0 {
+		return c.Layers
+	}
+	layers := []string{"dao"}
+	if c.Redis.Addr != "" {
+		layers = append(layers, "redis")
+	}
+	if c.CoalesceLookups {
+		layers = append(layers, "coalesce")
+	}
+	layers = append(layers, "store")
+	if c.WriteBehind.Enabled {
+		layers = append(layers, "write_behind")
+	}
+	return append(layers, "lru")
+}
+
+// Replicas configures reading units from postgres replicas.
+type Replicas struct {
+	// Hosts are hosts of replicas, they use the port and the credentials
+	// of Postgresql. Empty disables reading from replicas.
+	Hosts []string `mapstructure:"hosts"`
+	// Policy selects a replica for a read: round_robin or least_loaded.
+	Policy string `mapstructure:"policy"`
+	// MaxLag is the replication lag above which reads go to the primary.
+	MaxLag time.Duration `mapstructure:"max_lag"`
+	// RetryLag is the replication lag above which units not found
+	// on a replica are read from the primary.
+	RetryLag      time.Duration `mapstructure:"retry_lag"`
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+func (r Replicas) validate() error {
+	if r.Policy != postgresql.RoundRobin && r.Policy != postgresql.LeastLoaded {
+		return fmt.Errorf("replicas.policy must be %s or %s", postgresql.RoundRobin, postgresql.LeastLoaded)
+	}
+	if r.RetryLag < 0 {
+		return errors.New("replicas.retry_lag must not be negative")
+	}
+	return nil
+}
+
+// Leader configures election of the instance running maintenance jobs.
+type Leader struct {
+	// Name identifies the group of instances, one of them is elected.
+	Name string `mapstructure:"name"`
+	// Interval is how often other instances try to become the leader
+	// and the leader checks its connection.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// Tombstones configures removal of old tombstones of deleted units,
+// the removal is run by the leader.
+type Tombstones struct {
+	// TTL is the time tombstones are kept for, 0 keeps them forever.
+	// It should be more than Snapshot.MaxAge and the time an instance
+	// may go without a full sync.
+	TTL           time.Duration `mapstructure:"ttl"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
+// Sync configures scheduling of full and delta syncs.
+type Sync struct {
+	// Jitter spreads syncs of instances randomly by this fraction of the interval.
+	Jitter float64 `mapstructure:"jitter"`
+	// MinBackoff and MaxBackoff bound delays between retries of failed syncs.
+	MinBackoff time.Duration `mapstructure:"min_backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+}
+
+// FindByIDs configures lookups of many units at once.
+type FindByIDs struct {
+	// MaxIDs limits ids of a GetUnits request, 0 means no limit.
+	MaxIDs int `mapstructure:"max_ids"`
+	// ChunkSize is the max number of ids of a database query,
+	// longer lists are split into chunks queried concurrently.
+	ChunkSize   int `mapstructure:"chunk_size"`
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+// FetchAll configures reading all units by full syncs.
+type FetchAll struct {
+	// BatchSize is the number of units passed through the layers at once.
+	BatchSize int `mapstructure:"batch_size"`
+	// Partitions is the number of id ranges read concurrently,
+	// each one takes a connection of the pool.
+	Partitions int `mapstructure:"partitions"`
+}
+
+// Metrics configures stats of units layers.
+type Metrics struct {
+	// Instrument enables recording calls of every layer.
+	Instrument bool `mapstructure:"instrument"`
+	// Addr is host:port of the http server with /debug/vars, empty disables it.
+	Addr string `mapstructure:"addr"`
+}
+
+// WriteBehind configures acknowledging writes before they reach the database.
+type WriteBehind struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BatchSize is the max number of units written at once,
+	// a flush starts when that many writes are pending.
+	BatchSize int           `mapstructure:"batch_size"`
+	Interval  time.Duration `mapstructure:"interval"`
+	// Retries is how many times a failed batch is retried during a flush.
+	Retries int `mapstructure:"retries"`
+	// ShutdownTimeout bounds the final flush on shutdown.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+// Redis configures the cache of units shared by all instances.
+type Redis struct {
+	// Addr is host:port of the server, empty disables the redis cache.
+	Addr     string `mapstructure:"addr"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	// Prefix is prepended to ids of units to get their keys.
+	Prefix string `mapstructure:"prefix"`
+	// TTL is the time units are kept in redis for, it must be positive.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+func (r Redis) validate() error {
+	if r.Addr != "" && r.TTL <= 0 {
+		return errors.New("redis.ttl must be positive")
+	}
+	return nil
+}
+
+// Snapshot configures saving units to a local file for fast restarts.
+type Snapshot struct {
+	// Path is the snapshot file, empty disables snapshots.
+	Path     string        `mapstructure:"path"`
+	Interval time.Duration `mapstructure:"interval"`
+	// MaxAge is the age of the oldest snapshot loaded at startup, it should
+	// be less than the time tombstones of deleted units are kept.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// Cache configures the lru cache, the number of units is limited by LRUCacheSize.
+type Cache struct {
+	// MaxBytes limits total bytes of ids and data of cached units, 0 means no limit.
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// MaxEntryBytes is the size of the largest cached unit, 0 means a tenth of MaxBytes.
+	MaxEntryBytes int64 `mapstructure:"max_entry_bytes"`
+	// HeapLimit makes the cache shrink MaxBytes when the go heap approaches it,
+	// 0 disables adaptation.
+	HeapLimit uint64 `mapstructure:"heap_limit"`
+	// HeapCheckInterval is how often the heap is checked.
+	HeapCheckInterval time.Duration `mapstructure:"heap_check_interval"`
+	// Policy is the eviction policy: lru, 2q, arc or ttl.
+	// MaxBytes is only supported by lru and ttl.
+	Policy string `mapstructure:"policy"`
+	// TTL is how long units are fresh for with the ttl policy, units older
+	// than TTL are returned for StaleTTL more while they are refreshed.
+	TTL      time.Duration `mapstructure:"ttl"`
+	StaleTTL time.Duration `mapstructure:"stale_ttl"`
+}
+
+func (c Cache) validate() error {
+	if c.Policy == "ttl" && c.TTL <= 0 {
+		return errors.New("cache.ttl must be positive with the ttl policy")
+	}
+	if c.StaleTTL < 0 {
+		return errors.New("cache.stale_ttl must not be negative")
+	}
+	return nil
+}
+
+// NegativeCache configures caching of ids that were not found.
+type NegativeCache struct {
+	// Size is the max number of remembered ids, 0 disables negative caching.
+	Size int           `mapstructure:"size"`
+	TTL  time.Duration `mapstructure:"ttl"`
+	// Store enables negative caching in the store as well as in the lru cache.
+	Store bool `mapstructure:"store"`
+}
+
+// Notifications configures listening to writes made by other instances.
+type Notifications struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinBackoff and MaxBackoff bound delays between reconnection attempts.
+	MinBackoff time.Duration `mapstructure:"min_backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+}
+
+// Store configures the in-memory store of all units.
+type Store struct {
+	// MaxBytes limits memory accounted for stored units, 0 means no limit.
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// EvictionPolicy is "spill" to drop only data or "evict" to drop whole units.
+	EvictionPolicy string `mapstructure:"eviction_policy"`
+	// Lazy makes the store keep only ids and sizes until a unit is read.
+	Lazy bool `mapstructure:"lazy"`
+	// Shards is the number of parts of the store locked separately.
+	Shards int `mapstructure:"shards"`
+}
+
+// Deadline limits the time a grpc call may take.
+type Deadline struct {
+	// Default is applied when the client sent no deadline, 0 means none.
+	Default time.Duration `mapstructure:"default"`
+	// Max caps the deadline sent by the client, 0 means no cap.
+	Max time.Duration `mapstructure:"max"`
+}
+
+// Deadlines configures call deadlines, Methods overrides the defaults per
+// method. Keys are short method names (e.g. "getunits") or full grpc methods,
+// case insensitive.
+type Deadlines struct {
+	Deadline `mapstructure:",squash"`
+	Methods  map[string]Deadline `mapstructure:"methods"`
+}
+
+// Logging configures logging of incoming grpc requests.
+type Logging struct {
+	// Level of successful calls, failed calls are always logged with error level.
+	Level string `mapstructure:"level"`
+	// Methods overrides Level per method, keys are short method names
+	// (e.g. "getunits") or full grpc methods. Keys are case insensitive,
+	// "disabled" turns logging of the method off.
+	Methods map[string]string `mapstructure:"methods"`
+	// SampleRate is a fraction of successful calls that are logged.
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// MaxPayloadSize limits the size of payload fields that are logged as text.
+	MaxPayloadSize int `mapstructure:"max_payload_size"`
+	// RequestIDHeader is a metadata key the request id is taken from.
+	RequestIDHeader string `mapstructure:"request_id_header"`
+}
+
+func (l Logging) validate() error {
+	if _, err := zerolog.ParseLevel(l.Level); err != nil {
+		return fmt.Errorf("logging.level: %w", err)
+	}
+	for method, level := range l.Methods {
+		if _, err := zerolog.ParseLevel(level); err != nil {
+			return fmt.Errorf("logging.methods.%s: %w", method, err)
+		}
+	}
+	if l.SampleRate < 0 || l.SampleRate > 1 {
+		return errors.New("logging.sample_rate must be in [0, 1]")
+	}
+	return nil
+}
+
+// secondsToDurationHook decodes plain numbers into durations as seconds,
+// so settings that used to be seconds keep working, while strings
+// like "30s" are left to mapstructure.StringToTimeDurationHookFunc.
+func secondsToDurationHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(time.Duration(0)) || from == to {
+		return data, nil
+	}
+	var seconds float64
+	switch v := reflect.ValueOf(data); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		seconds = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		seconds = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		seconds = v.Float()
+	case reflect.String:
+		parsed, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return data, nil
+		}
+		seconds = parsed
+	default:
+		return data, nil
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
 }
 
 func New() (Config, error) {
 	configInstance := Config{}
-	if err := viper.Unmarshal(&configInstance); err != nil {
+	hooks := mapstructure.ComposeDecodeHookFunc(
+		secondsToDurationHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)
+	if err := viper.Unmarshal(&configInstance, viper.DecodeHook(hooks)); err != nil {
 		return Config{}, errors.New("can't load config structure")
 	}
+	if err := configInstance.Logging.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := configInstance.Replicas.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := configInstance.Cache.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := configInstance.Redis.validate(); err != nil {
+		return Config{}, err
+	}
 	return configInstance, nil
 }
 
@@ -36,6 +359,7 @@ func init() {
 	_ = err
 
 	viper.SetDefault("server_addr", ":10000")
+	viper.SetDefault("admin_addr", "127.0.0.1:10001")
 
 	// PostgreSQL
 	viper.SetDefault("postgresql.port", 5432)
@@ -50,7 +374,101 @@ func init() {
 	viper.SetDefault("postgresql.logger_enabled", false)
 	viper.SetDefault("postgresql.log_level", pgx.LogLevelError)
 	viper.SetDefault("postgresql.keep_alive", time.Second*15)
+	viper.SetDefault("postgresql.statement_timeout", 30*time.Second)
+
+	// Replicas
+	viper.SetDefault("replicas.hosts", []string{})
+	viper.SetDefault("replicas.policy", postgresql.RoundRobin)
+	viper.SetDefault("replicas.max_lag", 5*time.Second)
+	viper.SetDefault("replicas.retry_lag", 100*time.Millisecond)
+	viper.SetDefault("replicas.check_interval", time.Second)
 
 	viper.SetDefault("lru_cache_size", 500)
-	viper.SetDefault("fetch_units_timeout", 60*60) //1h
+	viper.SetDefault("fetch_units_timeout", time.Hour)
+	viper.SetDefault("delta_sync_interval", 5*time.Second)
+	viper.SetDefault("delta_sync_overlap", 30*time.Second)
+	viper.SetDefault("max_removed_ratio", 0.5)
+	viper.SetDefault("removal_confirmations", 3)
+	viper.SetDefault("coalesce_lookups", true)
+	viper.SetDefault("layers", []string{})
+
+	// Logging
+	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.sample_rate", 1.0)
+	viper.SetDefault("logging.max_payload_size", 256)
+	viper.SetDefault("logging.request_id_header", "x-request-id")
+
+	// Store
+	viper.SetDefault("store.max_bytes", 0)
+	viper.SetDefault("store.eviction_policy", "spill")
+	viper.SetDefault("store.lazy", false)
+	viper.SetDefault("store.shards", 32)
+
+	// Cache
+	viper.SetDefault("cache.max_bytes", 0)
+	viper.SetDefault("cache.max_entry_bytes", 0)
+	viper.SetDefault("cache.heap_limit", 0)
+	viper.SetDefault("cache.heap_check_interval", 10*time.Second)
+	viper.SetDefault("cache.policy", "lru")
+	viper.SetDefault("cache.ttl", 0)
+	viper.SetDefault("cache.stale_ttl", 0)
+
+	// Snapshot
+	viper.SetDefault("snapshot.path", "")
+	viper.SetDefault("snapshot.interval", 5*time.Minute)
+	viper.SetDefault("snapshot.max_age", 24*time.Hour)
+
+	// Redis
+	viper.SetDefault("redis.addr", "")
+	viper.SetDefault("redis.password", "")
+	viper.SetDefault("redis.db", 0)
+	viper.SetDefault("redis.prefix", "units:")
+	viper.SetDefault("redis.ttl", 10*time.Minute)
+
+	// Write behind
+	viper.SetDefault("write_behind.enabled", false)
+	viper.SetDefault("write_behind.batch_size", 500)
+	viper.SetDefault("write_behind.interval", time.Second)
+	viper.SetDefault("write_behind.retries", 3)
+	viper.SetDefault("write_behind.shutdown_timeout", 30*time.Second)
+
+	// Find by ids
+	viper.SetDefault("find_by_ids.max_ids", 100000)
+	viper.SetDefault("find_by_ids.chunk_size", 10000)
+	viper.SetDefault("find_by_ids.concurrency", 4)
+
+	// Fetch all
+	viper.SetDefault("fetch_all.batch_size", 1000)
+	viper.SetDefault("fetch_all.partitions", 1)
+
+	// Sync
+	viper.SetDefault("sync.jitter", 0.1)
+	viper.SetDefault("sync.min_backoff", time.Second)
+	viper.SetDefault("sync.max_backoff", time.Minute)
+
+	// Leader
+	viper.SetDefault("leader.name", "units")
+	viper.SetDefault("leader.interval", 5*time.Second)
+
+	// Tombstones
+	viper.SetDefault("tombstones.ttl", 7*24*time.Hour)
+	viper.SetDefault("tombstones.purge_interval", time.Hour)
+
+	// Metrics
+	viper.SetDefault("metrics.instrument", true)
+	viper.SetDefault("metrics.addr", "")
+
+	// Negative cache
+	viper.SetDefault("negative_cache.size", 10000)
+	viper.SetDefault("negative_cache.ttl", 30*time.Second)
+	viper.SetDefault("negative_cache.store", false)
+
+	// Notifications
+	viper.SetDefault("notifications.enabled", true)
+	viper.SetDefault("notifications.min_backoff", time.Second)
+	viper.SetDefault("notifications.max_backoff", 30*time.Second)
+
+	// Deadlines
+	viper.SetDefault("deadlines.default", 30*time.Second)
+	viper.SetDefault("deadlines.max", 0)
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AltMax/art-test/config"
+	"github.com/AltMax/art-test/postgresql"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/cache"
+	"github.com/AltMax/art-test/units/chain"
+	"github.com/AltMax/art-test/units/coalesce"
+	"github.com/AltMax/art-test/units/dao"
+	"github.com/AltMax/art-test/units/rediscache"
+	"github.com/AltMax/art-test/units/store"
+	"github.com/AltMax/art-test/units/writebehind"
+	"github.com/redis/go-redis/v9"
+)
+
+// newChainBuilder registers all layers of units, conf.LayerNames selects
+// the ones used and their order.
+func newChainBuilder(conf config.Config, postgresDB postgresql.DB) *chain.Builder {
+	return chain.NewBuilder().
+		Source("dao", func() (units.Units, error) {
+			return dao.NewUnits(postgresDB,
+				dao.WithFindChunks(conf.FindByIDs.ChunkSize, conf.FindByIDs.Concurrency),
+				dao.WithScan(conf.FetchAll.BatchSize, conf.FetchAll.Partitions),
+			), nil
+		}).
+		//общий для всех инстансов кэш в redis
+		Decorator("redis", func(next units.Units) (units.Units, error) {
+			if conf.Redis.Addr == "" {
+				return nil, errors.New("redis addr is not set")
+			}
+			client := redis.NewClient(&redis.Options{
+				Addr:     conf.Redis.Addr,
+				Password: conf.Redis.Password,
+				DB:       conf.Redis.DB,
+			})
+			return rediscache.NewCache(next, client,
+				rediscache.WithPrefix(conf.Redis.Prefix),
+				rediscache.WithTTL(conf.Redis.TTL),
+			)
+		}).
+		Decorator("coalesce", func(next units.Units) (units.Units, error) {
+			return coalesce.NewCoalescer(next), nil
+		}).
+		Decorator("store", func(next units.Units) (units.Units, error) {
+			opts, err := storeOptions(conf)
+			if err != nil {
+				return nil, err
+			}
+			return store.NewStore(next, opts...), nil
+		}).
+		//запись в базу пачками после ответа клиенту
+		Decorator("write_behind", func(next units.Units) (units.Units, error) {
+			s, ok := chain.Unwrap(next).(*store.Store)
+			if !ok {
+				return nil, errors.New("must be right over store")
+			}
+			writer, ok := s.Units.(units.BatchWriter)
+			if !ok {
+				return nil, fmt.Errorf("%T under store doesn't support batch writes", s.Units)
+			}
+			return writebehind.NewWriteBehind(s, writer,
+				writebehind.WithBatch(conf.WriteBehind.BatchSize, conf.WriteBehind.Interval),
+				writebehind.WithRetries(conf.WriteBehind.Retries, 100*time.Millisecond),
+			), nil
+		}).
+		Decorator("lru", func(next units.Units) (units.Units, error) {
+			opts, err := cacheOptions(conf)
+			if err != nil {
+				return nil, err
+			}
+			return cache.NewCache(next, conf.LRUCacheSize, opts...)
+		})
+}
+
+func storeOptions(conf config.Config) ([]store.Option, error) {
+	policy, err := store.ParseEvictionPolicy(conf.Store.EvictionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	opts := []store.Option{
+		store.WithMaxBytes(conf.Store.MaxBytes),
+		store.WithEvictionPolicy(policy),
+		store.WithMaxRemovedRatio(conf.MaxRemovedRatio, conf.RemovalConfirms),
+		store.WithShards(conf.Store.Shards),
+	}
+	if conf.Store.Lazy {
+		opts = append(opts, store.WithLazy())
+	}
+	if conf.NegativeCache.Store {
+		negative, err := newNegativeCache(conf.NegativeCache)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, store.WithNegativeCache(negative))
+	}
+	return opts, nil
+}
+
+func cacheOptions(conf config.Config) ([]cache.Option, error) {
+	policy, err := cache.ParsePolicy(conf.Cache.Policy)
+	if err != nil {
+		return nil, err
+	}
+	negative, err := newNegativeCache(conf.NegativeCache)
+	if err != nil {
+		return nil, err
+	}
+	return []cache.Option{
+		cache.WithPolicy(policy),
+		cache.WithTTL(conf.Cache.TTL, conf.Cache.StaleTTL),
+		cache.WithMaxRemovedRatio(conf.MaxRemovedRatio, conf.RemovalConfirms),
+		cache.WithMaxBytes(conf.Cache.MaxBytes, conf.Cache.MaxEntryBytes),
+		cache.WithHeapLimit(conf.Cache.HeapLimit),
+		cache.WithNegativeCache(negative),
+	}, nil
+}
+
+// newNegativeCache returns nil when negative caching is disabled,
+// layers work without it then.
+func newNegativeCache(conf config.NegativeCache) (*units.NegativeCache, error) {
+	if conf.Size <= 0 || conf.TTL <= 0 {
+		return nil, nil
+	}
+	return units.NewNegativeCache(conf.Size, conf.TTL)
+}
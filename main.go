@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/AltMax/art-test/config"
 	"github.com/AltMax/art-test/postgresql"
+	"github.com/AltMax/art-test/scheduler"
 	"github.com/AltMax/art-test/server"
 	"github.com/AltMax/art-test/services"
+	"github.com/AltMax/art-test/units"
 	"github.com/AltMax/art-test/units/cache"
+	"github.com/AltMax/art-test/units/chain"
 	"github.com/AltMax/art-test/units/dao"
+	"github.com/AltMax/art-test/units/instrument"
+	"github.com/AltMax/art-test/units/snapshot"
 	"github.com/AltMax/art-test/units/store"
+	"github.com/AltMax/art-test/units/writebehind"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -25,34 +39,298 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("create postgres session")
 	}
-	unitsDao := dao.NewUnits(postgresDB)
-	store := store.NewStore(unitsDao)
-	cache, err := cache.NewCache(store, conf.LRUCacheSize)
+	//чтение юнитов с реплик, пока они не отстают больше [conf.Replicas.MaxLag]
+	var replicated *postgresql.ReplicatedPool
+	if len(conf.Replicas.Hosts) > 0 {
+		replicas, err := postgresql.ReplicaPoolsFromConfig(conf.Postgresql.ReplicaConfigs(conf.Replicas.Hosts)...)
+		if err != nil {
+			log.Fatal().Err(err).Strs("hosts", conf.Replicas.Hosts).Msg("create postgres replica pools")
+		}
+		replicated = postgresql.NewReplicatedPool(postgresDB, replicas,
+			postgresql.WithReplicaPolicy(conf.Replicas.Policy),
+			postgresql.WithMaxReplicaLag(conf.Replicas.MaxLag),
+			postgresql.WithReplicaRetryLag(conf.Replicas.RetryLag),
+			postgresql.WithReplicaCheckPeriod(conf.Replicas.CheckInterval),
+		)
+		postgresDB = replicated
+		expvar.Publish("postgres_replicas", expvar.Func(func() interface{} {
+			return replicated.Replicas()
+		}))
+	}
+	//статистика вызовов каждого слоя
+	var stats *instrument.Registry
+	builder := newChainBuilder(conf, postgresDB)
+	if conf.Metrics.Instrument {
+		stats = instrument.NewRegistry()
+		builder.Wrap(stats.Wrap)
+	}
+	layers, err := builder.Build(conf.LayerNames())
 	if err != nil {
-		log.Fatal().Err(err).Int("lru-cache-size", conf.LRUCacheSize).Msg("create lru cache with size")
+		log.Fatal().Err(err).Strs("layers", conf.LayerNames()).Msg("build units layers")
+	}
+	log.Info().Strs("layers", layers.Names()).Msg("units layers")
+	expvar.Publish("units_layers", expvar.Func(func() interface{} {
+		return stats.Stats()
+	}))
+	if conf.Metrics.Addr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/debug/vars", expvar.Handler())
+			err := http.ListenAndServe(conf.Metrics.Addr, mux)
+			log.Error().Err(err).Msg("metrics server")
+		}()
+	}
+	//логгер в контексте, чтобы синхронизации писали в лог
+	ctx := log.Logger.WithContext(context.Background())
+	if replicated != nil {
+		go replicated.Monitor(ctx)
+	}
+	writeBehind, _ := layer[*writebehind.WriteBehind](layers, "write_behind")
+	var changesOptions []server.UnitsChangesOption
+	if writeBehind != nil {
+		//юниты, не записанные при сбросе, убираем и из слоев над write-behind;
+		//уведомления о юнитах с незаписанными изменениями пропускаем
+		writeBehind.EvictFrom(inMemoryLayers(layers)...)
+		changesOptions = append(changesOptions, server.WithPendingWrites(writeBehind.IsPending))
+		go writeBehind.Run(ctx)
 	}
-	ctx := context.Background()
 
-	fetchUnitsTimeout := time.Duration(conf.FetchUnitsTimeout) * time.Second
-	handler := server.NewUnitService(cache, fetchUnitsTimeout)
+	handler := server.NewUnitService(layers, conf.FetchUnitsTimeout,
+		server.WithDeltaSync(conf.DeltaSyncInterval, conf.DeltaSyncOverlap),
+		server.WithMaxGetUnits(conf.FindByIDs.MaxIDs),
+		server.WithSchedule(
+			scheduler.WithJitter(conf.Sync.Jitter),
+			scheduler.WithBackoff(conf.Sync.MinBackoff, conf.Sync.MaxBackoff),
+		),
+	)
 
-	//первая синхронизация при запуске
-	_, err = cache.FetchAll(ctx)
-	if err != nil {
-		log.Fatal().Err(err).Msg("first units fetch")
+	//уменьшение кэша при приближении к лимиту памяти
+	if cache, ok := layer[*cache.Cache](layers, "lru"); ok {
+		go cache.AdaptToMemoryPressure(ctx, conf.Cache.HeapCheckInterval)
 	}
+	unitsStore, hasStore := layer[*store.Store](layers, "store")
 
-	//синхронизация каждые [conf.FetchUnitsTimeout] секунд
+	//изменения, сделанные другими инстансами сервиса
+	if conf.Notifications.Enabled {
+		listener := postgresql.NewListener(
+			conf.Postgresql,
+			server.UnitsChangesChannel,
+			postgresql.WithListenerBackoff(conf.Notifications.MinBackoff, conf.Notifications.MaxBackoff),
+		)
+		go func() {
+			_ = listener.Listen(ctx, server.NewUnitsChanges(handler, inMemoryLayers(layers), changesOptions...))
+		}()
+		//слушаем канал до первой синхронизации, иначе записи между ними теряются;
+		//если подключиться не удалось, синхронизация повторится после подключения
+		select {
+		case <-listener.Ready():
+		case <-time.After(conf.Notifications.MaxBackoff):
+			log.Warn().Msg("units changes are not listened yet, units will be synced again once they are")
+		}
+	}
+
+	//юниты из снапшота, чтобы отвечать до полной синхронизации,
+	//после загрузки догоняем изменения дельтой
+	snapshots := conf.Snapshot.Path != "" && hasStore
+	if conf.Snapshot.Path != "" && !hasStore {
+		log.Warn().Msg("snapshots need the store layer")
+	}
+	restored := snapshots && restoreSnapshot(conf.Snapshot, unitsStore, handler)
+	if restored {
+		//полная синхронизация в фоне сразу после запуска расписания
+		//уберет юниты, удаленные после снапшота
+		handler.TriggerSync()
+		err = handler.SyncChanges(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("fetch units changes after snapshot, fetching all units")
+		}
+	}
+	if !restored || err != nil {
+		//первая синхронизация при запуске;
+		//юниты из снапшота отдаем, пока синхронизация повторяется в фоне
+		err = handler.Sync(ctx)
+		if err != nil && restored {
+			log.Error().Err(err).Msg("first units fetch after snapshot")
+		} else if err != nil {
+			log.Fatal().Err(err).Msg("first units fetch")
+		}
+	}
+	if snapshots {
+		writer := snapshot.NewWriter(conf.Snapshot.Path, unitsStore, func() time.Time {
+			return handler.SyncStatus().Watermark
+		})
+		go writer.WriteSometimes(ctx, conf.Snapshot.Interval)
+	}
+
+	//изменения каждые [conf.DeltaSyncInterval],
+	//полная синхронизация каждые [conf.FetchUnitsTimeout],
+	//ошибки повторяются с нарастающей задержкой
 	go handler.FetchUnitsSometimes(ctx)
 
+	//полная синхронизация вне расписания по SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Info().Msg("sync requested by SIGHUP")
+			handler.TriggerSync()
+		}
+	}()
+
+	//обслуживание базы выполняет только один инстанс,
+	//выбранный через advisory lock в postgres
+	maintenance := &leaderJobs{}
+	if conf.Tombstones.TTL > 0 {
+		maintenance.jobs = append(maintenance.jobs, purgeTombstones(conf, dao.NewUnits(postgresDB)))
+	}
+	if len(maintenance.jobs) > 0 {
+		leader := postgresql.NewLeader(conf.Postgresql, conf.Leader.Name, postgresql.WithLeaderInterval(conf.Leader.Interval))
+		go func() {
+			_ = leader.Run(ctx, maintenance)
+		}()
+	}
+
 	unitServer := server.New(&conf)
 	services.RegisterUnitServiceServer(unitServer, handler)
 	lis, err := net.Listen("tcp", conf.ServerAddr)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to listen unit service")
 	}
+	//админские методы без аутентификации, поэтому на отдельном адресе,
+	//по умолчанию доступном только локально
+	var adminServer *grpc.Server
+	if conf.AdminAddr != "" {
+		adminServer = server.New(&conf)
+		services.RegisterAdminServiceServer(adminServer, server.NewAdminService(handler, stats, adminLayers(layers)...))
+		adminLis, err := net.Listen("tcp", conf.AdminAddr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to listen admin service")
+		}
+		go func() {
+			if err := adminServer.Serve(adminLis); err != nil {
+				log.Error().Err(err).Msg("listen admin server")
+			}
+		}()
+	}
+	//по сигналу дожидаемся текущих запросов и сбрасываем отложенные записи
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Info().Str("signal", sig.String()).Msg("stopping unit server")
+		if adminServer != nil {
+			adminServer.GracefulStop()
+		}
+		unitServer.GracefulStop()
+	}()
+
 	log.Info().Msg("unit server started")
 	if err := unitServer.Serve(lis); err != nil {
 		log.Fatal().Err(err).Msg("listen unit server")
 	}
+
+	if writeBehind != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, conf.WriteBehind.ShutdownTimeout)
+		defer cancel()
+		if err := writeBehind.Close(shutdownCtx); err != nil {
+			log.Error().Err(err).Int("pending", writeBehind.Pending()).Msg("flush pending writes on shutdown")
+		}
+	}
+	log.Info().Msg("unit server stopped")
+}
+
+// leaderJobs are maintenance jobs run only by the leader of the instances.
+type leaderJobs struct {
+	jobs    []*scheduler.Job
+	running sync.WaitGroup
+}
+
+func (l *leaderJobs) Acquired(ctx context.Context) {
+	for _, job := range l.jobs {
+		l.running.Add(1)
+		go func(job *scheduler.Job) {
+			defer l.running.Done()
+			job.Run(ctx)
+		}(job)
+	}
+}
+
+// Released waits for the jobs to stop, so they don't run twice
+// when leadership is acquired again.
+func (l *leaderJobs) Released() {
+	l.running.Wait()
+}
+
+// purgeTombstones returns the job removing tombstones older than the ttl.
+func purgeTombstones(conf config.Config, units *dao.Units) *scheduler.Job {
+	return scheduler.New("purge tombstones", conf.Tombstones.PurgeInterval, func(ctx context.Context) error {
+		purged, err := units.PurgeTombstones(ctx, time.Now().UTC().Add(-conf.Tombstones.TTL))
+		if err == nil {
+			log.Info().Int64("purged", purged).Msg("tombstones purged")
+		}
+		return err
+	},
+		scheduler.WithJitter(conf.Sync.Jitter),
+		scheduler.WithBackoff(conf.Sync.MinBackoff, conf.Sync.MaxBackoff),
+	)
+}
+
+// layer returns the layer with the name if it's of type T.
+func layer[T units.Units](layers *chain.Chain, name string) (T, bool) {
+	l, ok := layers.Layer(name)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := l.(T)
+	return t, ok
+}
+
+// inMemoryLayers returns layers that can be invalidated, from the outermost one.
+func inMemoryLayers(layers *chain.Chain) []units.Layer {
+	inMemory := make([]units.Layer, 0)
+	for _, l := range layers.Layers() {
+		if layer, ok := l.Units.(units.Layer); ok {
+			inMemory = append(inMemory, layer)
+		}
+	}
+	return inMemory
+}
+
+func adminLayers(layers *chain.Chain) []server.AdminLayer {
+	admin := make([]server.AdminLayer, 0)
+	for _, l := range layers.Layers() {
+		if layer, ok := l.Units.(units.Layer); ok {
+			admin = append(admin, server.AdminLayer{Name: l.Name, Layer: layer})
+		}
+	}
+	return admin
+}
+
+// restoreSnapshot fills the store from the snapshot, missing, corrupt,
+// incompatible and too old snapshots are ignored.
+func restoreSnapshot(conf config.Snapshot, store *store.Store, handler *server.UnitService) bool {
+	start := time.Now()
+	snap, err := snapshot.Load(conf.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Info().Str("path", conf.Path).Msg("no snapshot to restore")
+		return false
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", conf.Path).Msg("ignore snapshot")
+		return false
+	}
+	if conf.MaxAge > 0 && time.Since(snap.TakenAt) > conf.MaxAge {
+		log.Warn().Time("taken_at", snap.TakenAt).Str("path", conf.Path).Msg("ignore outdated snapshot")
+		return false
+	}
+
+	store.Restore(snap.Units)
+	handler.Restored(snap.Watermark, len(snap.Units))
+	log.Info().
+		Int("units", len(snap.Units)).
+		Time("watermark", snap.Watermark).
+		Dur("duration", time.Since(start)).
+		Msg("snapshot restored")
+	return true
 }
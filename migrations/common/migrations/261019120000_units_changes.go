@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(UpUnitsChanges, DownUnitsChanges)
+}
+
+// units_deleted keeps tombstones of deleted units, so delta syncs see deletions.
+// A tombstone is removed when a unit with the same id is inserted again.
+var upUnitsChanges = `
+alter table units add column if not exists updated_at timestamp not null default (now() at time zone 'utc');
+create index if not exists units_updated_at_idx on units(updated_at);
+
+create table if not exists units_deleted (
+	id text not null,
+	deleted_at timestamp not null,
+	primary key(id)
+);
+create index if not exists units_deleted_deleted_at_idx on units_deleted(deleted_at);
+
+create or replace function units_touch() returns trigger as $$
+begin
+	new.updated_at := clock_timestamp() at time zone 'utc';
+	if tg_op = 'INSERT' then
+		delete from units_deleted where id = new.id;
+	end if;
+	return new;
+end;
+$$ language plpgsql;
+
+create or replace function units_tombstone() returns trigger as $$
+begin
+	insert into units_deleted(id, deleted_at)
+	values (old.id, clock_timestamp() at time zone 'utc')
+	on conflict(id) do update set deleted_at = excluded.deleted_at;
+	return old;
+end;
+$$ language plpgsql;
+
+drop trigger if exists units_touch on units;
+create trigger units_touch before insert or update on units
+	for each row execute procedure units_touch();
+
+drop trigger if exists units_tombstone on units;
+create trigger units_tombstone after delete on units
+	for each row execute procedure units_tombstone();
+`
+
+var downUnitsChanges = `
+drop trigger if exists units_tombstone on units;
+drop trigger if exists units_touch on units;
+drop function if exists units_tombstone();
+drop function if exists units_touch();
+drop table if exists units_deleted;
+alter table units drop column if exists updated_at;
+`
+
+func UpUnitsChanges(tx *sql.Tx) error {
+	_, err := tx.Exec(upUnitsChanges)
+	return err
+}
+
+func DownUnitsChanges(tx *sql.Tx) error {
+	_, err := tx.Exec(downUnitsChanges)
+	return err
+}
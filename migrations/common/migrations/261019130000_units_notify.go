@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(UpUnitsNotify, DownUnitsNotify)
+}
+
+// Every write of units is notified on the units_changes channel with the id
+// as payload. Ids that don't fit into a notification are sent as an empty
+// payload, listeners have to drop all units in that case.
+var upUnitsNotify = `
+create or replace function units_notify() returns trigger as $$
+declare
+	unit_id text;
+begin
+	if tg_op = 'DELETE' then
+		unit_id := old.id;
+	else
+		unit_id := new.id;
+	end if;
+	if octet_length(unit_id) > 7000 then
+		unit_id := '';
+	end if;
+	perform pg_notify('units_changes', unit_id);
+	return null;
+end;
+$$ language plpgsql;
+
+drop trigger if exists units_notify on units;
+create trigger units_notify after insert or update or delete on units
+	for each row execute procedure units_notify();
+`
+
+var downUnitsNotify = `
+drop trigger if exists units_notify on units;
+drop function if exists units_notify();
+`
+
+func UpUnitsNotify(tx *sql.Tx) error {
+	_, err := tx.Exec(upUnitsNotify)
+	return err
+}
+
+func DownUnitsNotify(tx *sql.Tx) error {
+	_, err := tx.Exec(downUnitsNotify)
+	return err
+}
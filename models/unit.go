@@ -10,6 +10,9 @@ type Unit struct {
 	ID        string
 	Data      []byte
 	CreatedAt time.Time
+	// UpdatedAt is set by the database on every write, it's zero for writes
+	// acknowledged before they are written to the database.
+	UpdatedAt time.Time
 }
 
 func (u *Unit) Proto() *services.Unit {
@@ -23,6 +26,13 @@ func (u *Unit) Proto() *services.Unit {
 	}
 }
 
+// NewerThan reports whether u is a later version of the unit than other.
+// UpdatedAt works as a version: the database sets it with clock_timestamp()
+// after the row is locked, so later writes of a unit have later UpdatedAt.
+func (u *Unit) NewerThan(other *Unit) bool {
+	return u.UpdatedAt.After(other.UpdatedAt)
+}
+
 type Units []*Unit
 
 func (us Units) Proto() []*services.Unit {
@@ -33,6 +43,33 @@ func (us Units) Proto() []*services.Unit {
 	return pb
 }
 
+func (us Units) IDs() []string {
+	ids := make([]string, 0, len(us))
+	for _, u := range us {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+// LastUpdatedAt returns the latest UpdatedAt of units.
+func (us Units) LastUpdatedAt() time.Time {
+	var last time.Time
+	for _, u := range us {
+		if u.UpdatedAt.After(last) {
+			last = u.UpdatedAt
+		}
+	}
+	return last
+}
+
+// Changes are units written and deleted since a watermark.
+type Changes struct {
+	Updated Units
+	Deleted []string
+	// Watermark is the latest change seen, the next delta starts from it.
+	Watermark time.Time
+}
+
 func timeToMilliseconds(t time.Time) int64 {
 	return t.UnixNano() / int64(time.Millisecond)
 }
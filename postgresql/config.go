@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -29,6 +30,8 @@ type Config struct {
 	LogLevel          pgx.LogLevel  `mapstructure:"log_level"`
 	KeepAlive         time.Duration `mapstructure:"keep_alive"`
 	Schema            string        `mapstructure:"schema"`
+	// StatementTimeout is set on every connection of the pool, 0 means none.
+	StatementTimeout time.Duration `mapstructure:"statement_timeout"`
 }
 
 // ConnString return connection string
@@ -115,7 +118,7 @@ func NewConnectionPool(conf Config, opts ...ConnectionPoolOption) (DB, error) {
 		return nil, err
 	}
 
-	connectionPool := &ConnectionPool{Pool: p}
+	connectionPool := &ConnectionPool{Pool: p, statementTimeout: conf.StatementTimeout}
 
 	for _, opt := range opts {
 		err := opt(connectionPool)
@@ -133,6 +136,10 @@ func (conf Config) ApplyPoolConfig(poolConfig *pgxpool.Config) {
 	poolConfig.MinConns = conf.MinConnections
 	poolConfig.MaxConnLifetime = conf.MaxConnectionAge
 	poolConfig.HealthCheckPeriod = conf.HealthCheckPeriod
+	if conf.StatementTimeout > 0 {
+		// sent in the startup message, so it costs no round trips
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(conf.StatementTimeout.Milliseconds(), 10)
+	}
 	if conf.LoggerEnabled {
 		dialer := &wrappedDialer{
 			&net.Dialer{
@@ -149,6 +156,8 @@ func (conf Config) ApplyPoolConfig(poolConfig *pgxpool.Config) {
 	}
 }
 
+// ReplicaPoolsFromConfig returns pools of replicas, they connect lazily,
+// so a replica that is down doesn't prevent the start.
 func ReplicaPoolsFromConfig(replicaConfigs ...Config) ([]*pgxpool.Pool, error) {
 	var replicas []*pgxpool.Pool
 	for _, conf := range replicaConfigs {
@@ -158,6 +167,7 @@ func ReplicaPoolsFromConfig(replicaConfigs ...Config) ([]*pgxpool.Pool, error) {
 		}
 
 		conf.ApplyPoolConfig(replicaPoolConfig)
+		replicaPoolConfig.LazyConnect = true
 
 		replica, err := pgxpool.ConnectConfig(context.Background(), replicaPoolConfig)
 		if err != nil {
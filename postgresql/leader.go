@@ -0,0 +1,166 @@
+package postgresql
+
+import (
+	"context"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultLeaderInterval = 5 * time.Second
+
+// LeaderHandler is notified when the instance gains and loses leadership.
+type LeaderHandler interface {
+	// Acquired is called when the instance becomes the leader, ctx is done
+	// when leadership is lost. It must not block, jobs it starts should stop
+	// when ctx is done.
+	Acquired(ctx context.Context)
+	// Released is called after ctx passed to Acquired is done, Acquired is
+	// not called again until it returns, so it may wait for the jobs to stop.
+	Released()
+}
+
+// leaderConn is the part of *pgx.Conn used by Leader.
+type leaderConn interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+}
+
+// Leader elects one of the instances using the same name by a session level
+// advisory lock held on a dedicated connection. The lock is released by
+// postgres when the connection is lost, the leader notices that by checking
+// the connection every interval, so for up to an interval two instances may
+// consider themselves leaders and jobs run by the leader should be idempotent.
+type Leader struct {
+	conf     Config
+	name     string
+	key      int64
+	interval time.Duration
+	leading  int32
+	connect  func(ctx context.Context) (leaderConn, error)
+}
+
+type LeaderOption func(*Leader)
+
+// WithLeaderInterval sets how often other instances try to take the lock
+// and the leader checks its connection.
+func WithLeaderInterval(interval time.Duration) LeaderOption {
+	return func(l *Leader) {
+		l.interval = interval
+	}
+}
+
+// NewLeader returns a leader of the instances using the same name,
+// the name is hashed into the key of the advisory lock.
+func NewLeader(conf Config, name string, opts ...LeaderOption) *Leader {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+	l := &Leader{
+		conf:     conf,
+		name:     name,
+		key:      int64(hash.Sum64()),
+		interval: defaultLeaderInterval,
+	}
+	l.connect = l.connectConfig
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// IsLeader reports whether the instance holds the lock.
+func (l *Leader) IsLeader() bool {
+	return atomic.LoadInt32(&l.leading) == 1
+}
+
+// Run takes part in elections until ctx is done, the lock is released
+// when ctx is done.
+func (l *Leader) Run(ctx context.Context, handler LeaderHandler) error {
+	for {
+		err := l.run(ctx, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Warn().Err(err).Str("leader", l.name).Dur("retry_in", l.interval).Msg("leader election connection lost")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.interval):
+		}
+	}
+}
+
+func (l *Leader) connectConfig(ctx context.Context) (leaderConn, error) {
+	connConfig, err := pgx.ParseConfig(l.conf.ConnString())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.ConnectConfig(ctx, connConfig)
+}
+
+func (l *Leader) run(ctx context.Context, handler LeaderHandler) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		var acquired bool
+		err := conn.QueryRow(ctx, `select pg_try_advisory_lock($1)`, l.key).Scan(&acquired)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return l.lead(ctx, conn, ticker, handler)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead keeps the lock until ctx is done or the connection is lost.
+func (l *Leader) lead(ctx context.Context, conn leaderConn, ticker *time.Ticker, handler LeaderHandler) error {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	atomic.StoreInt32(&l.leading, 1)
+	// registered first, so leadership is released however lead returns
+	defer func() {
+		atomic.StoreInt32(&l.leading, 0)
+		cancel()
+		handler.Released()
+		log.Info().Str("leader", l.name).Msg("leadership released")
+	}()
+	log.Info().Str("leader", l.name).Msg("leadership acquired")
+	handler.Acquired(leaderCtx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			unlockCtx, cancelUnlock := context.WithTimeout(context.Background(), l.interval)
+			defer cancelUnlock()
+			_, _ = conn.Exec(unlockCtx, `select pg_advisory_unlock($1)`, l.key)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		pingCtx, cancelPing := context.WithTimeout(ctx, l.interval)
+		err := conn.Ping(pingCtx)
+		cancelPing()
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRow struct {
+	acquired bool
+	err      error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*bool) = r.acquired
+	return nil
+}
+
+// fakeLeaderConn grants the lock when true is sent to locks and fails
+// a ping with an error sent to pings, like a lost connection.
+type fakeLeaderConn struct {
+	locks   chan bool
+	pings   chan error
+	unlocks chan string
+}
+
+func newFakeLeaderConn() *fakeLeaderConn {
+	return &fakeLeaderConn{
+		locks:   make(chan bool),
+		pings:   make(chan error, 1),
+		unlocks: make(chan string, 1),
+	}
+}
+
+func (c *fakeLeaderConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	select {
+	case <-ctx.Done():
+		return fakeRow{err: ctx.Err()}
+	case acquired := <-c.locks:
+		return fakeRow{acquired: acquired}
+	}
+}
+
+func (c *fakeLeaderConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	c.unlocks <- sql
+	return nil, nil
+}
+
+func (c *fakeLeaderConn) Ping(ctx context.Context) error {
+	select {
+	case err := <-c.pings:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (c *fakeLeaderConn) Close(ctx context.Context) error {
+	return nil
+}
+
+// fakeLeaderHandler reports whether ctx passed to Acquired
+// was done when Released was called.
+type fakeLeaderHandler struct {
+	ctx    context.Context
+	events chan string
+}
+
+func (h *fakeLeaderHandler) Acquired(ctx context.Context) {
+	h.ctx = ctx
+	h.events <- "acquired"
+}
+
+func (h *fakeLeaderHandler) Released() {
+	if h.ctx.Err() == nil {
+		h.events <- "released while leading"
+		return
+	}
+	h.events <- "released"
+}
+
+func Test_Leader_Release(t *testing.T) {
+	l := NewLeader(Config{}, "maintenance", WithLeaderInterval(time.Millisecond))
+	conn := newFakeLeaderConn()
+	l.connect = func(ctx context.Context) (leaderConn, error) {
+		return conn, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := &fakeLeaderHandler{events: make(chan string, 1)}
+	done := make(chan error)
+	go func() {
+		done <- l.Run(ctx, handler)
+	}()
+
+	// the lock is held by another instance first
+	conn.locks <- false
+	require.False(t, l.IsLeader())
+	conn.locks <- true
+	require.Equal(t, "acquired", <-handler.events)
+	require.True(t, l.IsLeader())
+
+	cancel()
+	require.Equal(t, "released", <-handler.events)
+	require.Equal(t, "select pg_advisory_unlock($1)", <-conn.unlocks)
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.False(t, l.IsLeader())
+}
+
+func Test_Leader_Lost(t *testing.T) {
+	l := NewLeader(Config{}, "maintenance", WithLeaderInterval(time.Millisecond))
+	conns := make(chan *fakeLeaderConn, 2)
+	first, second := newFakeLeaderConn(), newFakeLeaderConn()
+	conns <- first
+	conns <- second
+	l.connect = func(ctx context.Context) (leaderConn, error) {
+		return <-conns, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := &fakeLeaderHandler{events: make(chan string, 1)}
+	go func() {
+		_ = l.Run(ctx, handler)
+	}()
+
+	first.locks <- true
+	require.Equal(t, "acquired", <-handler.events)
+
+	// leadership is released when the connection is lost
+	// and acquired again on a new connection
+	first.pings <- errors.New("connection lost")
+	require.Equal(t, "released", <-handler.events)
+	require.False(t, l.IsLeader())
+
+	second.locks <- true
+	require.Equal(t, "acquired", <-handler.events)
+	require.True(t, l.IsLeader())
+}
+
+// panickingLeaderHandler panics in Acquired.
+type panickingLeaderHandler struct {
+	fakeLeaderHandler
+}
+
+func (h *panickingLeaderHandler) Acquired(ctx context.Context) {
+	h.ctx = ctx
+	panic("acquired")
+}
+
+func Test_Leader_AcquiredPanics(t *testing.T) {
+	l := NewLeader(Config{}, "maintenance", WithLeaderInterval(time.Millisecond))
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	handler := &panickingLeaderHandler{fakeLeaderHandler{events: make(chan string, 1)}}
+
+	require.Panics(t, func() {
+		_ = l.lead(context.Background(), newFakeLeaderConn(), ticker, handler)
+	})
+	require.Equal(t, "released", <-handler.events)
+	require.False(t, l.IsLeader())
+}
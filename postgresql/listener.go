@@ -0,0 +1,138 @@
+package postgresql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultListenerMinBackoff = time.Second
+	defaultListenerMaxBackoff = 30 * time.Second
+)
+
+// NotificationHandler handles notifications received by Listener.
+type NotificationHandler interface {
+	// Connected is called every time listening starts, reconnected is true
+	// when notifications might have been missed while the connection was down.
+	Connected(reconnected bool)
+	Notify(payload string)
+}
+
+// listenConn is the part of *pgx.Conn used by Listener.
+type listenConn interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Listener receives notifications of a channel on a dedicated connection,
+// the connection is reestablished with exponential backoff when it's lost.
+type Listener struct {
+	conf       Config
+	channel    string
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	connect    func(ctx context.Context) (listenConn, error)
+	after      func(d time.Duration) <-chan time.Time
+
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+type ListenerOption func(*Listener)
+
+// WithListenerBackoff sets delays between reconnection attempts,
+// the delay doubles after every failed attempt up to max.
+func WithListenerBackoff(min, max time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.minBackoff = min
+		l.maxBackoff = max
+	}
+}
+
+func NewListener(conf Config, channel string, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		conf:       conf,
+		channel:    channel,
+		minBackoff: defaultListenerMinBackoff,
+		maxBackoff: defaultListenerMaxBackoff,
+		ready:      make(chan struct{}),
+	}
+	l.connect = l.connectConfig
+	l.after = time.After
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Ready is closed when listening starts for the first time, notifications
+// of writes made before that are not received.
+func (l *Listener) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// Listen passes notifications to handler until ctx is done.
+func (l *Listener) Listen(ctx context.Context, handler NotificationHandler) error {
+	backoff := l.minBackoff
+	connected := false
+	for {
+		err := l.listen(ctx, func() {
+			handler.Connected(connected)
+			connected = true
+			backoff = l.minBackoff
+			l.readyOnce.Do(func() {
+				close(l.ready)
+			})
+		}, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Warn().Err(err).Str("channel", l.channel).Dur("retry_in", backoff).Msg("postgres listener disconnected")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.after(backoff):
+		}
+		backoff *= 2
+		if backoff > l.maxBackoff {
+			backoff = l.maxBackoff
+		}
+	}
+}
+
+func (l *Listener) connectConfig(ctx context.Context) (listenConn, error) {
+	connConfig, err := pgx.ParseConfig(l.conf.ConnString())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.ConnectConfig(ctx, connConfig)
+}
+
+func (l *Listener) listen(ctx context.Context, connected func(), handler NotificationHandler) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "listen "+pgx.Identifier{l.channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handler.Notify(notification.Payload)
+	}
+}
@@ -0,0 +1,149 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn delivers notifications sent to it and fails with an error
+// sent to it, like a lost connection.
+type fakeConn struct {
+	notifications chan *pgconn.Notification
+	errs          chan error
+	listened      chan string
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		notifications: make(chan *pgconn.Notification),
+		errs:          make(chan error),
+		listened:      make(chan string),
+	}
+}
+
+func (c *fakeConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	c.listened <- sql
+	return nil, nil
+}
+
+func (c *fakeConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case n := <-c.notifications:
+		return n, nil
+	case err := <-c.errs:
+		return nil, err
+	}
+}
+
+func (c *fakeConn) Close(ctx context.Context) error {
+	return nil
+}
+
+type fakeNotificationHandler struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *fakeNotificationHandler) Connected(reconnected bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, fmt.Sprintf("connected %v", reconnected))
+}
+
+func (h *fakeNotificationHandler) Notify(payload string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, "notify "+payload)
+}
+
+func (h *fakeNotificationHandler) Events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.events...)
+}
+
+func Test_Listener_Reconnect(t *testing.T) {
+	l := NewListener(Config{}, "units_changes", WithListenerBackoff(time.Second, 3*time.Second))
+
+	// connecting fails three times before the first connection,
+	// then after the first connection is lost
+	conns := []*fakeConn{newFakeConn(), newFakeConn()}
+	failures := []int{3, 1}
+	var (
+		mu      sync.Mutex
+		backoff []time.Duration
+	)
+	l.connect = func(ctx context.Context) (listenConn, error) {
+		if failures[0] > 0 {
+			failures[0]--
+			return nil, errors.New("connection refused")
+		}
+		failures = failures[1:]
+		conn := conns[0]
+		conns = conns[1:]
+		return conn, nil
+	}
+	l.after = func(d time.Duration) <-chan time.Time {
+		mu.Lock()
+		backoff = append(backoff, d)
+		mu.Unlock()
+		return time.After(time.Millisecond)
+	}
+	first, second := conns[0], conns[1]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := &fakeNotificationHandler{}
+	done := make(chan error)
+	go func() {
+		done <- l.Listen(ctx, handler)
+	}()
+
+	require.Equal(t, `listen "units_changes"`, <-first.listened)
+	<-l.Ready()
+	first.notifications <- &pgconn.Notification{Payload: "1"}
+	first.errs <- errors.New("connection lost")
+
+	<-second.listened
+	second.notifications <- &pgconn.Notification{Payload: "2"}
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.Equal(t, []string{"connected false", "notify 1", "connected true", "notify 2"}, handler.Events())
+	// the backoff doubles up to the max and is reset by a connection
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, time.Second, 2 * time.Second}, backoff)
+}
+
+func Test_Listener_Ready(t *testing.T) {
+	l := NewListener(Config{}, "units_changes")
+	conn := newFakeConn()
+	l.connect = func(ctx context.Context) (listenConn, error) {
+		return conn, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = l.Listen(ctx, &fakeNotificationHandler{})
+	}()
+
+	select {
+	case <-l.Ready():
+		t.Fatal("ready before listen")
+	case <-time.After(10 * time.Millisecond):
+	}
+	<-conn.listened
+	select {
+	case <-l.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("not ready after listen")
+	}
+}
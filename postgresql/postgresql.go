@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgconn"
@@ -42,6 +43,12 @@ func (er errRow) Scan(...interface{}) error {
 // ConnectionPool is struct with connection pool
 type ConnectionPool struct {
 	*pgxpool.Pool
+	statementTimeout time.Duration
+}
+
+// StatementTimeout returns statement_timeout of connections of the pool.
+func (p *ConnectionPool) StatementTimeout() time.Duration {
+	return p.statementTimeout
 }
 
 func (p *ConnectionPool) ExecxCtx(ctx context.Context, builder sq.Sqlizer) (commandTag pgconn.CommandTag, err error) {
@@ -0,0 +1,347 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	RoundRobin  = "round_robin"
+	LeastLoaded = "least_loaded"
+
+	defaultMaxReplicaLag      = 5 * time.Second
+	defaultReplicaRetryLag    = 100 * time.Millisecond
+	defaultReplicaCheckPeriod = time.Second
+)
+
+// replicaLagQuery returns replication lag in seconds, a replica that replayed
+// everything it received has no lag even if the primary had no writes for long.
+const replicaLagQuery = `select case
+	when not pg_is_in_recovery() or pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() then 0
+	else coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)
+end`
+
+type replica struct {
+	*ConnectionPool
+	host string
+	// healthy is 1 when the last check succeeded and the lag was acceptable.
+	healthy int32
+	lag     int64
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+// Lag returns the replication lag as of the last check.
+func (r *replica) Lag() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.lag))
+}
+
+// ReplicaStatus describes a replica as of the last check.
+type ReplicaStatus struct {
+	Host    string        `json:"host"`
+	Healthy bool          `json:"healthy"`
+	Lag     time.Duration `json:"lag"`
+}
+
+// ReplicatedPool is a DB on the primary that sends reads tolerating
+// replication lag to replicas, see RunOnReplica and ReplicaQuery.
+// Replicas are checked by Monitor, reads go to the primary when no replica
+// is reachable with the lag below the max one.
+type ReplicatedPool struct {
+	DB
+	replicas    []*replica
+	policy      string
+	maxLag      time.Duration
+	retryLag    time.Duration
+	checkPeriod time.Duration
+	next        uint32
+}
+
+type ReplicatedPoolOption func(*ReplicatedPool)
+
+// WithReplicaPolicy sets how a replica is selected for a read:
+// RoundRobin or LeastLoaded, the replica with the fewest acquired connections.
+func WithReplicaPolicy(policy string) ReplicatedPoolOption {
+	return func(p *ReplicatedPool) {
+		p.policy = policy
+	}
+}
+
+// WithMaxReplicaLag sets the lag above which a replica gets no reads.
+func WithMaxReplicaLag(lag time.Duration) ReplicatedPoolOption {
+	return func(p *ReplicatedPool) {
+		p.maxLag = lag
+	}
+}
+
+// WithReplicaRetryLag sets the lag above which reads run by
+// RunOnReplicaOrPrimary that missed rows on a replica are run on the primary.
+func WithReplicaRetryLag(lag time.Duration) ReplicatedPoolOption {
+	return func(p *ReplicatedPool) {
+		p.retryLag = lag
+	}
+}
+
+// WithReplicaCheckPeriod sets how often Monitor checks replicas.
+func WithReplicaCheckPeriod(period time.Duration) ReplicatedPoolOption {
+	return func(p *ReplicatedPool) {
+		p.checkPeriod = period
+	}
+}
+
+// NewReplicatedPool returns a pool sending reads to replicas,
+// see ReplicaPoolsFromConfig. Replicas get no reads until Monitor checks them.
+func NewReplicatedPool(primary DB, replicas []*pgxpool.Pool, opts ...ReplicatedPoolOption) *ReplicatedPool {
+	p := &ReplicatedPool{
+		DB:          primary,
+		replicas:    make([]*replica, 0, len(replicas)),
+		policy:      RoundRobin,
+		maxLag:      defaultMaxReplicaLag,
+		retryLag:    defaultReplicaRetryLag,
+		checkPeriod: defaultReplicaCheckPeriod,
+	}
+	for _, pool := range replicas {
+		p.replicas = append(p.replicas, &replica{
+			ConnectionPool: &ConnectionPool{Pool: pool, statementTimeout: statementTimeoutOf(pool.Config())},
+			host:           pool.Config().ConnConfig.Host,
+		})
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// statementTimeoutOf returns statement_timeout set by Config.ApplyPoolConfig.
+func statementTimeoutOf(poolConfig *pgxpool.Config) time.Duration {
+	ms, _ := strconv.ParseInt(poolConfig.ConnConfig.RuntimeParams["statement_timeout"], 10, 64)
+	return time.Duration(ms) * time.Millisecond
+}
+
+// StatementTimeout returns statement_timeout of the primary.
+func (p *ReplicatedPool) StatementTimeout() time.Duration {
+	if pool, ok := p.DB.(interface{ StatementTimeout() time.Duration }); ok {
+		return pool.StatementTimeout()
+	}
+	return 0
+}
+
+// ReplicaConfigs returns configs of replicas on the hosts,
+// they differ from the config only by the host.
+func (c Config) ReplicaConfigs(hosts []string) []Config {
+	configs := make([]Config, 0, len(hosts))
+	for _, host := range hosts {
+		conf := c
+		conf.Host = host
+		configs = append(configs, conf)
+	}
+	return configs
+}
+
+// Monitor checks lag of replicas every check period until ctx is done.
+func (p *ReplicatedPool) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(p.checkPeriod)
+	defer ticker.Stop()
+	for {
+		for _, r := range p.replicas {
+			p.check(ctx, r)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *ReplicatedPool) check(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, p.checkPeriod)
+	defer cancel()
+
+	var seconds float64
+	err := r.QueryRowCtx(ctx, replicaLagQuery).Scan(&seconds)
+	if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		return
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+	if err == nil {
+		atomic.StoreInt64(&r.lag, int64(lag))
+	}
+
+	healthy := err == nil && lag <= p.maxLag
+	if p.setHealthy(r, healthy) {
+		l := log.Info()
+		if !healthy {
+			l = log.Warn().Err(err)
+		}
+		l.Str("host", r.host).Dur("lag", lag).Bool("healthy", healthy).Msg("postgres replica state changed")
+	}
+}
+
+// setHealthy reports whether the state of the replica has changed.
+func (p *ReplicatedPool) setHealthy(r *replica, healthy bool) bool {
+	var value int32
+	if healthy {
+		value = 1
+	}
+	return atomic.SwapInt32(&r.healthy, value) != value
+}
+
+// Replicas returns states of the replicas.
+func (p *ReplicatedPool) Replicas() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		statuses = append(statuses, ReplicaStatus{
+			Host:    r.host,
+			Healthy: r.isHealthy(),
+			Lag:     r.Lag(),
+		})
+	}
+	return statuses
+}
+
+// pick returns a healthy replica selected by the policy, nil when there is none.
+func (p *ReplicatedPool) pick() *replica {
+	if len(p.replicas) == 0 {
+		return nil
+	}
+	if p.policy == LeastLoaded {
+		var (
+			picked   *replica
+			acquired int32
+		)
+		for _, r := range p.replicas {
+			if !r.isHealthy() {
+				continue
+			}
+			if n := r.Stat().AcquiredConns(); picked == nil || n < acquired {
+				picked, acquired = r, n
+			}
+		}
+		return picked
+	}
+
+	start := int(atomic.AddUint32(&p.next, 1))
+	for i := range p.replicas {
+		r := p.replicas[(start+i)%len(p.replicas)]
+		if r.isHealthy() {
+			return r
+		}
+	}
+	return nil
+}
+
+// Replica returns a healthy replica or the primary.
+func (p *ReplicatedPool) Replica() DB {
+	if r := p.pick(); r != nil {
+		return r
+	}
+	return p.DB
+}
+
+// runOnReplica runs fn on a replica, fn is retried on the primary when
+// the replica fails to run it. The replica gets no reads until the next
+// successful check.
+func (p *ReplicatedPool) runOnReplica(ctx context.Context, fn func(db DB) error) error {
+	r := p.pick()
+	if r == nil {
+		return fn(p.DB)
+	}
+	err := fn(r)
+	if err == nil || ctx.Err() != nil || !isReplicaError(err) {
+		return err
+	}
+
+	if p.setHealthy(r, false) {
+		log.Warn().Err(err).Str("host", r.host).Msg("postgres replica failed, reading from the primary")
+	}
+	return fn(p.DB)
+}
+
+func (p *ReplicatedPool) ReplicaQuery(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return p.Replica().QueryCtx(ctx, sql, args...)
+}
+
+func (p *ReplicatedPool) ReplicaQueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return p.Replica().QueryRowCtx(ctx, sql, args...)
+}
+
+func (p *ReplicatedPool) ReplicaSendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return p.Replica().SendBatch(ctx, b)
+}
+
+// Close closes the replicas and the primary.
+func (p *ReplicatedPool) Close() error {
+	for _, r := range p.replicas {
+		_ = r.Close()
+	}
+	return p.DB.Close()
+}
+
+// isReplicaError reports whether err is caused by the replica rather than
+// by the query: connection errors, shutdowns and conflicts with recovery.
+func isReplicaError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P") || pgErr.Code == "40001"
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || pgconn.SafeToRetry(err)
+}
+
+// RunOnReplica runs fn like RunWithStatementTimeout on a replica when db is
+// a ReplicatedPool, on db otherwise. fn must tolerate replication lag.
+func RunOnReplica(ctx context.Context, db DB, fn func(db DB) error) error {
+	return RunOnReplicaOrPrimary(ctx, db, fn, func() bool {
+		return false
+	})
+}
+
+// RunOnReplicaOrPrimary runs fn like RunOnReplica. fn that succeeded on
+// a replica lagging behind more than the retry lag (see WithReplicaRetryLag)
+// is run again on the primary when missed reports that rows were not found,
+// they might be written after the last transaction replayed by the replica.
+// Misses on replicas that lag less are final, so they are served by replicas.
+func RunOnReplicaOrPrimary(ctx context.Context, db DB, fn func(db DB) error, missed func() bool) error {
+	replicated, ok := db.(*ReplicatedPool)
+	if !ok {
+		return RunWithStatementTimeout(ctx, db, fn)
+	}
+	var used *replica
+	err := replicated.runOnReplica(ctx, func(db DB) error {
+		used, _ = db.(*replica)
+		return RunWithStatementTimeout(ctx, db, fn)
+	})
+	if err != nil || used == nil || used.Lag() <= replicated.retryLag || !missed() {
+		return err
+	}
+	return RunWithStatementTimeout(ctx, replicated.DB, fn)
+}
+
+// RunScanOnReplica runs fn like RunScan on a replica when db is
+// a ReplicatedPool, on db otherwise. fn must tolerate replication lag.
+func RunScanOnReplica(ctx context.Context, db DB, fn func(db DB) error) error {
+	replicated, ok := db.(*ReplicatedPool)
+	if !ok {
+		return RunScan(ctx, db, fn)
+	}
+	return replicated.runOnReplica(ctx, func(db DB) error {
+		return RunScan(ctx, db, fn)
+	})
+}
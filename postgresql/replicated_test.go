@@ -0,0 +1,110 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func testReplicatedPool(replicas int) *ReplicatedPool {
+	p := NewReplicatedPool(&ConnectionPool{}, nil)
+	for i := 0; i < replicas; i++ {
+		p.replicas = append(p.replicas, &replica{ConnectionPool: &ConnectionPool{}, healthy: 1})
+	}
+	return p
+}
+
+func Test_ReplicatedPool_RoundRobin(t *testing.T) {
+	p := testReplicatedPool(3)
+
+	picked := make(map[DB]int)
+	for i := 0; i < 6; i++ {
+		picked[p.Replica()]++
+	}
+	require.Len(t, picked, 3)
+	for _, r := range p.replicas {
+		require.Equal(t, 2, picked[r])
+	}
+
+	// unhealthy replicas are skipped, the primary is used when none is left
+	p.setHealthy(p.replicas[0], false)
+	p.setHealthy(p.replicas[1], false)
+	require.Equal(t, DB(p.replicas[2]), p.Replica())
+	p.setHealthy(p.replicas[2], false)
+	require.Equal(t, p.DB, p.Replica())
+}
+
+func Test_ReplicatedPool_Fallback(t *testing.T) {
+	p := testReplicatedPool(1)
+	ctx := context.Background()
+
+	var used []DB
+	err := p.runOnReplica(ctx, func(db DB) error {
+		used = append(used, db)
+		if db != p.DB {
+			return &net.OpError{Op: "read", Err: errors.New("connection reset")}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []DB{p.replicas[0], p.DB}, used)
+	require.False(t, p.replicas[0].isHealthy())
+
+	// errors of queries are not retried
+	p.setHealthy(p.replicas[0], true)
+	used = nil
+	queryErr := &pgconn.PgError{Code: "42P01"}
+	err = p.runOnReplica(ctx, func(db DB) error {
+		used = append(used, db)
+		return queryErr
+	})
+	require.ErrorIs(t, err, queryErr)
+	require.Equal(t, []DB{p.replicas[0]}, used)
+	require.True(t, p.replicas[0].isHealthy())
+}
+
+func Test_RunOnReplicaOrPrimary(t *testing.T) {
+	p := testReplicatedPool(1)
+	p.replicas[0].lag = int64(time.Second)
+	ctx := context.Background()
+
+	var used []DB
+	run := func(missed bool) error {
+		used = nil
+		return RunOnReplicaOrPrimary(ctx, p, func(db DB) error {
+			used = append(used, db)
+			return nil
+		}, func() bool {
+			return missed
+		})
+	}
+
+	require.NoError(t, run(true))
+	require.Equal(t, []DB{p.replicas[0], p.DB}, used)
+	require.NoError(t, run(false))
+	require.Equal(t, []DB{p.replicas[0]}, used)
+
+	// misses on a replica lagging less than the retry lag are final
+	p.replicas[0].lag = int64(p.retryLag)
+	require.NoError(t, run(true))
+	require.Equal(t, []DB{p.replicas[0]}, used)
+
+	// results of the primary are final
+	p.setHealthy(p.replicas[0], false)
+	require.NoError(t, run(true))
+	require.Equal(t, []DB{p.DB}, used)
+}
+
+func Test_isReplicaError(t *testing.T) {
+	require.True(t, isReplicaError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.True(t, isReplicaError(&pgconn.PgError{Code: "57P01"}))
+	require.True(t, isReplicaError(&pgconn.PgError{Code: "40001"}))
+	require.False(t, isReplicaError(&pgconn.PgError{Code: "23505"}))
+	require.False(t, isReplicaError(errors.New("too many units removed")))
+	require.False(t, isReplicaError(context.DeadlineExceeded))
+}
@@ -0,0 +1,95 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RunWithStatementTimeout runs fn in a transaction with statement_timeout set
+// to the time left until the ctx deadline, so postgres cancels queries that
+// outlive the caller instead of holding a pool connection. The transaction
+// costs three round trips, so it's not used when statement_timeout set on
+// connections of the pool is already within the deadline. Without a deadline
+// fn is run on db directly.
+func RunWithStatementTimeout(ctx context.Context, db DB, fn func(db DB) error) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return fn(db)
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return context.DeadlineExceeded
+	}
+	if !needsStatementTimeout(db, timeout) {
+		return fn(db)
+	}
+	return runWithStatementTimeout(ctx, db, timeout, fn)
+}
+
+// RunScan runs fn like RunWithStatementTimeout, but statement_timeout of the
+// pool doesn't apply to it. Postgres counts time spent sending rows to a slow
+// client against the timeout, so scans of large tables would be cancelled.
+func RunScan(ctx context.Context, db DB, fn func(db DB) error) error {
+	if poolStatementTimeout(db) <= 0 {
+		return RunWithStatementTimeout(ctx, db, fn)
+	}
+
+	// 0 disables the timeout
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	return runWithStatementTimeout(ctx, db, timeout, fn)
+}
+
+func runWithStatementTimeout(ctx context.Context, db DB, timeout time.Duration, fn func(db DB) error) (err error) {
+	timeoutMs := (timeout + time.Millisecond - 1).Milliseconds()
+
+	tx, err := db.BeginCtx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		p := recover()
+		switch {
+		case p != nil:
+			_ = tx.RollbackCtx(context.Background())
+			panic(p)
+		case err != nil:
+			_ = tx.RollbackCtx(context.Background())
+		default:
+			err = tx.CommitCtx(ctx)
+		}
+	}()
+
+	_, err = tx.ExecCtx(ctx, fmt.Sprintf("set local statement_timeout = %d", timeoutMs))
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	return err
+}
+
+// needsStatementTimeout reports whether queries with the timeout need
+// statement_timeout of their own, which is the case when the timeout of
+// the pool is longer. Pools without the setting and transactions always do.
+func needsStatementTimeout(db DB, timeout time.Duration) bool {
+	poolTimeout := poolStatementTimeout(db)
+	return poolTimeout <= 0 || timeout < poolTimeout
+}
+
+// poolStatementTimeout returns statement_timeout of connections of the pool,
+// 0 when there is none or db is not a pool.
+func poolStatementTimeout(db DB) time.Duration {
+	pool, ok := db.(interface{ StatementTimeout() time.Duration })
+	if !ok {
+		return 0
+	}
+	return pool.StatementTimeout()
+}
@@ -0,0 +1,46 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_needsStatementTimeout(t *testing.T) {
+	pool := &ConnectionPool{statementTimeout: 30 * time.Second}
+	require.False(t, needsStatementTimeout(pool, 30*time.Second))
+	require.False(t, needsStatementTimeout(pool, time.Minute))
+	// a shorter deadline gets its own timeout however close it is
+	require.True(t, needsStatementTimeout(pool, 20*time.Second))
+	require.True(t, needsStatementTimeout(pool, time.Second))
+
+	// without statement_timeout on connections any deadline needs its own
+	require.True(t, needsStatementTimeout(&ConnectionPool{}, time.Hour))
+	require.True(t, needsStatementTimeout(&Transaction{}, time.Hour))
+
+	replicated := NewReplicatedPool(pool, nil)
+	require.True(t, needsStatementTimeout(replicated, 20*time.Second))
+	require.False(t, needsStatementTimeout(replicated, 30*time.Second))
+}
+
+func Test_RunScan(t *testing.T) {
+	// without statement_timeout on connections and a deadline
+	// there is nothing to set, so no transaction is needed
+	pool := &ConnectionPool{}
+	var used DB
+	err := RunScan(context.Background(), pool, func(db DB) error {
+		used = db
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, DB(pool), used)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = RunScan(ctx, &ConnectionPool{statementTimeout: time.Second}, func(db DB) error {
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Job runs a function periodically. Failed runs are retried with
+// an exponential backoff instead of waiting for the whole interval,
+// a run can be requested at any time with Trigger.
+type Job struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+
+	jitter     float64
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	rand       func() float64
+	now        func() time.Time
+
+	trigger chan struct{}
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status describes runs of a job.
+type Status struct {
+	LastStartedAt time.Time
+	LastSuccessAt time.Time
+	LastFailureAt time.Time
+	LastDuration  time.Duration
+	LastError     error
+	// Failures is the number of failed runs since the last success.
+	Failures  int
+	NextRunAt time.Time
+}
+
+type Option func(*Job)
+
+// WithJitter spreads runs randomly by up to fraction of the delay
+// in both directions, so instances started together don't run at once.
+func WithJitter(fraction float64) Option {
+	return func(j *Job) {
+		j.jitter = fraction
+	}
+}
+
+// WithBackoff sets delays of retries after failures, the delay doubles
+// from min to max, it never exceeds the interval.
+func WithBackoff(min, max time.Duration) Option {
+	return func(j *Job) {
+		j.minBackoff = min
+		j.maxBackoff = max
+	}
+}
+
+func New(name string, interval time.Duration, fn func(ctx context.Context) error, opts ...Option) *Job {
+	j := &Job{
+		name:       name,
+		interval:   interval,
+		fn:         fn,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+		rand:       rand.Float64,
+		now:        time.Now,
+		trigger:    make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Run runs the job every interval until ctx is done, the first run
+// happens after the interval.
+func (j *Job) Run(ctx context.Context) {
+	logger := zerolog.Ctx(ctx).With().Str("job", j.name).Logger()
+
+	timer := time.NewTimer(j.schedule())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-j.trigger:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		err := j.run(ctx)
+		delay := j.schedule()
+		if err != nil && ctx.Err() == nil {
+			logger.Error().Err(err).Int("failures", j.Status().Failures).Dur("retry_in", delay).Msg("job failed")
+		}
+		timer.Reset(delay)
+	}
+}
+
+// Trigger makes the job run as soon as the current run finishes,
+// triggers made while a run is pending are merged.
+func (j *Job) Trigger() {
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *Job) run(ctx context.Context) error {
+	start := j.now()
+	j.mu.Lock()
+	j.status.LastStartedAt = start
+	j.mu.Unlock()
+
+	err := j.fn(ctx)
+
+	finish := j.now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.LastDuration = finish.Sub(start)
+	j.status.LastError = err
+	if err != nil {
+		j.status.LastFailureAt = finish
+		j.status.Failures++
+	} else {
+		j.status.LastSuccessAt = finish
+		j.status.Failures = 0
+	}
+	return err
+}
+
+// schedule returns the delay before the next run and records the time of it.
+func (j *Job) schedule() time.Duration {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delay := j.delay(j.status.Failures)
+	j.status.NextRunAt = j.now().Add(delay)
+	return delay
+}
+
+// delay is the interval or the backoff after failures, with jitter.
+func (j *Job) delay(failures int) time.Duration {
+	delay := j.interval
+	if failures > 0 {
+		backoff := j.minBackoff
+		for i := 1; i < failures && backoff < j.maxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > j.maxBackoff {
+			backoff = j.maxBackoff
+		}
+		if backoff < delay {
+			delay = backoff
+		}
+	}
+	if j.jitter > 0 {
+		delay += time.Duration((2*j.rand() - 1) * j.jitter * float64(delay))
+	}
+	return delay
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_delay(t *testing.T) {
+	j := New("test", time.Minute, nil, WithBackoff(time.Second, 10*time.Second))
+
+	require.Equal(t, time.Minute, j.delay(0))
+	require.Equal(t, time.Second, j.delay(1))
+	require.Equal(t, 2*time.Second, j.delay(2))
+	require.Equal(t, 8*time.Second, j.delay(4))
+	require.Equal(t, 10*time.Second, j.delay(5))
+	require.Equal(t, 10*time.Second, j.delay(100))
+
+	// backoff never exceeds the interval
+	j.interval = 5 * time.Second
+	require.Equal(t, 5*time.Second, j.delay(10))
+
+	j = New("test", time.Minute, nil, WithJitter(0.5))
+	j.rand = func() float64 { return 0 }
+	require.Equal(t, 30*time.Second, j.delay(0))
+	j.rand = func() float64 { return 1 }
+	require.Equal(t, 90*time.Second, j.delay(0))
+}
+
+func Test_Run_Backoff(t *testing.T) {
+	var calls int32
+	j := New("test", time.Hour, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}, WithBackoff(time.Millisecond, time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go j.Run(ctx)
+
+	// the first run is triggered, failed runs are retried without waiting an hour,
+	// the run after the success is scheduled in an hour
+	j.Trigger()
+	require.Eventually(t, func() bool {
+		status := j.Status()
+		return !status.LastSuccessAt.IsZero() && status.NextRunAt.After(status.LastSuccessAt.Add(time.Minute))
+	}, time.Second, time.Millisecond)
+
+	status := j.Status()
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	require.Equal(t, 0, status.Failures)
+	require.NoError(t, status.LastError)
+	require.False(t, status.LastFailureAt.IsZero())
+	require.False(t, status.LastFailureAt.After(status.LastSuccessAt))
+}
+
+func Test_Trigger_Merged(t *testing.T) {
+	j := New("test", time.Hour, nil)
+	j.Trigger()
+	j.Trigger()
+	require.Len(t, j.trigger, 1)
+}
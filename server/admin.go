@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AltMax/art-test/services"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/instrument"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminLayer is an in-memory layer exposed by AdminService.
+type AdminLayer struct {
+	Name  string
+	Layer units.Layer
+}
+
+type AdminService struct {
+	unitService *UnitService
+	stats       *instrument.Registry
+	layers      []AdminLayer
+}
+
+// NewAdminService exposes stats of layers instrumented by stats,
+// stats may be nil when layers are not instrumented.
+func NewAdminService(unitService *UnitService, stats *instrument.Registry, layers ...AdminLayer) *AdminService {
+	return &AdminService{
+		unitService: unitService,
+		stats:       stats,
+		layers:      layers,
+	}
+}
+
+func (a *AdminService) Invalidate(ctx context.Context, req *services.InvalidateRequest) (*services.Empty, error) {
+	if len(req.Ids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one id is required")
+	}
+
+	layers, err := a.selectLayers(req.Layers)
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range layers {
+		layer.Layer.Invalidate(req.Ids...)
+	}
+
+	return &services.Empty{}, nil
+}
+
+func (a *AdminService) InvalidateAll(ctx context.Context, req *services.InvalidateAllRequest) (*services.Empty, error) {
+	layers, err := a.selectLayers(req.Layers)
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range layers {
+		layer.Layer.InvalidateAll()
+	}
+
+	return &services.Empty{}, nil
+}
+
+// Resync starts a full sync in background and returns immediately,
+// the progress is reported by GetSyncStatus.
+func (a *AdminService) Resync(ctx context.Context, req *services.Empty) (*services.SyncStatus, error) {
+	// the sync outlives the request, so only the logger is taken from ctx
+	syncCtx := loggerFromContext(ctx).WithContext(context.Background())
+	err := a.unitService.StartSync(syncCtx)
+	if errors.Is(err, ErrSyncInProgress) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a.unitService.SyncStatus().Proto(), nil
+}
+
+func (a *AdminService) GetSyncStatus(ctx context.Context, req *services.Empty) (*services.SyncStatus, error) {
+	return a.unitService.SyncStatus().Proto(), nil
+}
+
+func (a *AdminService) GetLayerSizes(ctx context.Context, req *services.Empty) (*services.GetLayerSizesResponse, error) {
+	resp := &services.GetLayerSizesResponse{
+		Layers: make([]*services.LayerSize, 0, len(a.layers)),
+	}
+	for _, layer := range a.layers {
+		size := &services.LayerSize{
+			Name:    layer.Name,
+			Entries: int64(layer.Layer.Len()),
+		}
+		if sized, ok := layer.Layer.(units.SizedLayer); ok {
+			size.Bytes = sized.Bytes()
+		}
+		if negative, ok := layer.Layer.(units.NegativeCachedLayer); ok {
+			stats := negative.NegativeStats()
+			size.NegativeEntries = int64(stats.Entries)
+			size.NegativeHits = stats.Hits
+			size.NegativeMisses = stats.Misses
+		}
+		resp.Layers = append(resp.Layers, size)
+	}
+	return resp, nil
+}
+
+func (a *AdminService) GetLayerStats(ctx context.Context, req *services.Empty) (*services.GetLayerStatsResponse, error) {
+	stats := a.stats.Stats()
+	resp := &services.GetLayerStatsResponse{
+		Layers: make([]*services.LayerStats, 0, len(stats)),
+	}
+	for _, layer := range stats {
+		pb := &services.LayerStats{
+			Name:    layer.Layer,
+			Methods: make([]*services.MethodStats, 0, len(layer.Methods)),
+		}
+		for _, method := range layer.Methods {
+			pb.Methods = append(pb.Methods, &services.MethodStats{
+				Method:       method.Method,
+				Calls:        method.Calls,
+				Errors:       method.Errors,
+				NotFound:     method.NotFound,
+				Requested:    method.Requested,
+				Returned:     method.Returned,
+				TotalLatency: method.Total.Microseconds(),
+				P50Latency:   method.P50.Microseconds(),
+				P99Latency:   method.P99.Microseconds(),
+			})
+		}
+		resp.Layers = append(resp.Layers, pb)
+	}
+	return resp, nil
+}
+
+func (a *AdminService) selectLayers(names []string) ([]AdminLayer, error) {
+	if len(names) == 0 {
+		return a.layers, nil
+	}
+
+	selected := make([]AdminLayer, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, layer := range a.layers {
+			if layer.Name == name {
+				selected = append(selected, layer)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown layer %q", name)
+		}
+	}
+	return selected, nil
+}
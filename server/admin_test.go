@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/config"
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/services"
+	"github.com/AltMax/art-test/units/instrument"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeLayer struct {
+	sync.Mutex
+	ids map[string]struct{}
+}
+
+func newFakeLayer(ids ...string) *fakeLayer {
+	l := &fakeLayer{ids: make(map[string]struct{})}
+	for _, id := range ids {
+		l.ids[id] = struct{}{}
+	}
+	return l
+}
+
+func (l *fakeLayer) Invalidate(ids ...string) {
+	l.Lock()
+	defer l.Unlock()
+	for _, id := range ids {
+		delete(l.ids, id)
+	}
+}
+
+func (l *fakeLayer) InvalidateAll() {
+	l.Lock()
+	defer l.Unlock()
+	l.ids = make(map[string]struct{})
+}
+
+func (l *fakeLayer) Len() int {
+	l.Lock()
+	defer l.Unlock()
+	return len(l.ids)
+}
+
+type sizedFakeLayer struct {
+	*fakeLayer
+}
+
+func (l sizedFakeLayer) Bytes() int64 {
+	return int64(l.Len()) * 100
+}
+
+type adminHandler struct {
+	unitsMock          *mocks.Units
+	cache              *fakeLayer
+	store              *fakeLayer
+	stats              *instrument.Registry
+	adminServiceClient services.AdminServiceClient
+}
+
+func newTestAdminHandler() *adminHandler {
+	conf, err := config.New()
+	if err != nil {
+		panic(err)
+	}
+
+	handler := &adminHandler{
+		unitsMock: &mocks.Units{},
+		cache:     newFakeLayer("1", "2"),
+		store:     newFakeLayer("1", "2", "3"),
+	}
+
+	handler.stats = instrument.NewRegistry()
+	unitService := NewUnitService(handler.stats.Wrap("dao", handler.unitsMock), time.Hour)
+	admin := NewAdminService(
+		unitService,
+		handler.stats,
+		AdminLayer{Name: "cache", Layer: handler.cache},
+		AdminLayer{Name: "store", Layer: sizedFakeLayer{handler.store}},
+	)
+
+	listener, conn := newMockGrpcConnAndListener()
+	srv := New(&conf)
+	services.RegisterAdminServiceServer(srv, admin)
+	handler.adminServiceClient = services.NewAdminServiceClient(conn)
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			panic(err)
+		}
+	}()
+	return handler
+}
+
+func Test_Admin_Invalidate(t *testing.T) {
+	handler := newTestAdminHandler()
+	ctx := context.Background()
+
+	_, err := handler.adminServiceClient.Invalidate(ctx, &services.InvalidateRequest{})
+	st, _ := status.FromError(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	_, err = handler.adminServiceClient.Invalidate(ctx, &services.InvalidateRequest{Ids: []string{"1"}, Layers: []string{"unknown"}})
+	st, _ = status.FromError(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	_, err = handler.adminServiceClient.Invalidate(ctx, &services.InvalidateRequest{Ids: []string{"1"}, Layers: []string{"cache"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, handler.cache.Len())
+	require.Equal(t, 3, handler.store.Len())
+
+	_, err = handler.adminServiceClient.Invalidate(ctx, &services.InvalidateRequest{Ids: []string{"2", "3"}})
+	require.NoError(t, err)
+	require.Equal(t, 0, handler.cache.Len())
+	require.Equal(t, 1, handler.store.Len())
+}
+
+func Test_Admin_InvalidateAll(t *testing.T) {
+	handler := newTestAdminHandler()
+	ctx := context.Background()
+
+	_, err := handler.adminServiceClient.InvalidateAll(ctx, &services.InvalidateAllRequest{Layers: []string{"store"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, handler.cache.Len())
+	require.Equal(t, 0, handler.store.Len())
+
+	_, err = handler.adminServiceClient.InvalidateAll(ctx, &services.InvalidateAllRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 0, handler.cache.Len())
+}
+
+func Test_Admin_GetLayerSizes(t *testing.T) {
+	handler := newTestAdminHandler()
+	ctx := context.Background()
+
+	resp, err := handler.adminServiceClient.GetLayerSizes(ctx, &services.Empty{})
+	require.NoError(t, err)
+	require.Equal(t, []*services.LayerSize{
+		{Name: "cache", Entries: 2},
+		{Name: "store", Entries: 3, Bytes: 300},
+	}, resp.Layers)
+}
+
+func Test_Admin_GetLayerStats(t *testing.T) {
+	handler := newTestAdminHandler()
+	ctx := context.Background()
+
+	handler.unitsMock.On("FetchAll", mock.Anything).Return(models.Units{randomUnit(), randomUnit()}, nil)
+	_, err := handler.adminServiceClient.Resync(ctx, &services.Empty{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(handler.stats.Stats()[0].Methods) > 0
+	}, time.Second, time.Millisecond)
+
+	resp, err := handler.adminServiceClient.GetLayerStats(ctx, &services.Empty{})
+	require.NoError(t, err)
+	require.Len(t, resp.Layers, 1)
+	require.Equal(t, "dao", resp.Layers[0].Name)
+	require.Len(t, resp.Layers[0].Methods, 1)
+	method := resp.Layers[0].Methods[0]
+	require.Equal(t, "Scan", method.Method)
+	require.Equal(t, uint64(1), method.Calls)
+	require.Equal(t, uint64(2), method.Returned)
+}
+
+func Test_Admin_Resync(t *testing.T) {
+	handler := newTestAdminHandler()
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	handler.unitsMock.On("FetchAll", mock.Anything).
+		Run(func(mock.Arguments) { <-release }).
+		Return(models.Units{randomUnit(), randomUnit()}, nil)
+
+	resp, err := handler.adminServiceClient.Resync(ctx, &services.Empty{})
+	require.NoError(t, err)
+	require.True(t, resp.InProgress)
+	require.NotZero(t, resp.LastStartedAt)
+
+	_, err = handler.adminServiceClient.Resync(ctx, &services.Empty{})
+	st, _ := status.FromError(err)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+
+	close(release)
+	require.Eventually(t, func() bool {
+		resp, err = handler.adminServiceClient.GetSyncStatus(ctx, &services.Empty{})
+		return err == nil && !resp.InProgress
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, int64(2), resp.SyncedUnits)
+	require.Empty(t, resp.LastError)
+	require.NotZero(t, resp.LastSuccessAt)
+}
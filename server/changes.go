@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AltMax/art-test/units"
+	"github.com/rs/zerolog/log"
+)
+
+// UnitsChangesChannel is the postgres channel writes of units are notified on.
+const UnitsChangesChannel = "units_changes"
+
+// UnitsChanges evicts units written by other instances from the in-memory
+// layers, it handles notifications of UnitsChangesChannel. The instance that
+// made the write evicts the unit as well, it's reloaded on the next read.
+type UnitsChanges struct {
+	unitService *UnitService
+	layers      []units.Layer
+	isPending   func(id string) bool
+}
+
+type UnitsChangesOption func(*UnitsChanges)
+
+// WithPendingWrites makes notifications of units with writes not flushed
+// to the database yet ignored, the notified write is older than the pending
+// one and evicting it would serve the older unit from the database.
+func WithPendingWrites(isPending func(id string) bool) UnitsChangesOption {
+	return func(c *UnitsChanges) {
+		c.isPending = isPending
+	}
+}
+
+// NewUnitsChanges takes layers ordered from the outermost one,
+// they are invalidated starting from the innermost one, so an outer layer
+// isn't refilled from a stale inner layer.
+func NewUnitsChanges(unitService *UnitService, layers []units.Layer, opts ...UnitsChangesOption) *UnitsChanges {
+	c := &UnitsChanges{
+		unitService: unitService,
+		layers:      layers,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Connected starts a full sync after reconnect, since changes made
+// while the connection was down are not notified. The same goes for the first
+// connection made after units were loaded.
+func (c *UnitsChanges) Connected(reconnected bool) {
+	status := c.unitService.SyncStatus()
+	loaded := !status.LastSuccessAt.IsZero() || !status.RestoredAt.IsZero()
+	if !reconnected && !loaded {
+		return
+	}
+	err := c.unitService.StartSync(context.Background())
+	if err != nil && !errors.Is(err, ErrSyncInProgress) {
+		log.Error().Err(err).Msg("start sync after reconnect")
+	}
+}
+
+// Notify evicts the unit with the id in payload, an empty payload means
+// the id didn't fit into the notification and all units are evicted.
+func (c *UnitsChanges) Notify(payload string) {
+	if payload != "" && c.isPending != nil && c.isPending(payload) {
+		return
+	}
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		if payload == "" {
+			c.layers[i].InvalidateAll()
+		} else {
+			c.layers[i].Invalidate(payload)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UnitsChanges_Notify(t *testing.T) {
+	cache := newFakeLayer("1", "2")
+	store := newFakeLayer("1", "2", "3")
+	pending := map[string]bool{"2": true}
+	changes := NewUnitsChanges(NewUnitService(&mocks.Units{}, time.Hour), []units.Layer{cache, store},
+		WithPendingWrites(func(id string) bool { return pending[id] }))
+
+	changes.Notify("1")
+	require.Equal(t, 1, cache.Len())
+	require.Equal(t, 2, store.Len())
+
+	// units with pending writes are kept
+	changes.Notify("2")
+	require.Equal(t, 1, cache.Len())
+	require.Equal(t, 2, store.Len())
+
+	changes.Notify("")
+	require.Equal(t, 0, cache.Len())
+	require.Equal(t, 0, store.Len())
+}
+
+func Test_UnitsChanges_Connected(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{randomUnit()}, nil)
+	unitService := NewUnitService(unitsMock, time.Hour)
+	changes := NewUnitsChanges(unitService, nil)
+
+	changes.Connected(false)
+	unitsMock.AssertNotCalled(t, "FetchAll", mock.Anything)
+
+	changes.Connected(true)
+	require.Eventually(t, func() bool {
+		return !unitService.SyncStatus().LastSuccessAt.IsZero()
+	}, time.Second, 10*time.Millisecond)
+	unitsMock.AssertNumberOfCalls(t, "FetchAll", 1)
+}
+
+func Test_UnitsChanges_Connected_AfterSync(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{randomUnit()}, nil)
+	unitService := NewUnitService(unitsMock, time.Hour)
+	changes := NewUnitsChanges(unitService, nil)
+
+	// listening started after the first sync, writes between them were missed
+	err := unitService.Sync(context.Background())
+	require.NoError(t, err)
+	synced := unitService.SyncStatus().LastSuccessAt
+	changes.Connected(false)
+	require.Eventually(t, func() bool {
+		return unitService.SyncStatus().LastSuccessAt.After(synced)
+	}, time.Second, 10*time.Millisecond)
+	unitsMock.AssertNumberOfCalls(t, "FetchAll", 2)
+}
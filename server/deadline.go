@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/AltMax/art-test/config"
+	"google.golang.org/grpc"
+)
+
+type deadlines struct {
+	defaults config.Deadline
+	methods  map[string]config.Deadline
+}
+
+func newDeadlines(conf config.Deadlines) *deadlines {
+	methods := make(map[string]config.Deadline, len(conf.Methods))
+	for method, deadline := range conf.Methods {
+		methods[strings.ToLower(method)] = deadline
+	}
+	return &deadlines{
+		defaults: conf.Deadline,
+		methods:  methods,
+	}
+}
+
+func (d *deadlines) deadlineFor(fullMethod string) config.Deadline {
+	if deadline, ok := d.methods[strings.ToLower(fullMethod)]; ok {
+		return deadline
+	}
+	if deadline, ok := d.methods[strings.ToLower(path.Base(fullMethod))]; ok {
+		return deadline
+	}
+	return d.defaults
+}
+
+// deadlineMiddleware sets the default deadline of the method when the client
+// sent none and shortens deadlines that are longer than the allowed maximum.
+// The deadline is passed down to postgres as statement_timeout by dao.Units.
+func (d *deadlines) deadlineMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, cancel := d.withDeadline(ctx, d.deadlineFor(info.FullMethod))
+	defer cancel()
+	return handler(ctx, req)
+}
+
+func (d *deadlines) withDeadline(ctx context.Context, limits config.Deadline) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if limits.Max > 0 && time.Until(deadline) > limits.Max {
+			return context.WithTimeout(ctx, limits.Max)
+		}
+		return ctx, func() {}
+	}
+
+	timeout := limits.Default
+	if limits.Max > 0 && (timeout == 0 || timeout > limits.Max) {
+		timeout = limits.Max
+	}
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
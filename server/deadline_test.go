@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func callWithDeadlines(d *deadlines, ctx context.Context, method string) (deadline time.Time, ok bool) {
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	_, _ = d.deadlineMiddleware(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, ok = ctx.Deadline()
+		return nil, nil
+	})
+	return
+}
+
+func Test_deadlineMiddleware(t *testing.T) {
+	d := newDeadlines(config.Deadlines{
+		Deadline: config.Deadline{Default: 10 * time.Second},
+		Methods: map[string]config.Deadline{
+			"GetUnits":                           {Default: time.Second, Max: 5 * time.Second},
+			"/test.art.unit.UnitService/GetUnit": {Max: 2 * time.Second},
+			"Delete":                             {},
+		},
+	})
+	const service = "/test.art.unit.UnitService/"
+
+	// default of the service is applied
+	deadline, ok := callWithDeadlines(d, context.Background(), service+"Create")
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+
+	// default of the method is applied
+	deadline, ok = callWithDeadlines(d, context.Background(), service+"GetUnits")
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	// client deadline is capped
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, ok = callWithDeadlines(d, ctx, service+"GetUnits")
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(5*time.Second), deadline, 100*time.Millisecond)
+
+	// shorter client deadline is kept
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	clientDeadline, _ := ctx.Deadline()
+	deadline, ok = callWithDeadlines(d, ctx, service+"GetUnits")
+	require.True(t, ok)
+	require.Equal(t, clientDeadline, deadline)
+
+	// max is used when there is no default
+	deadline, ok = callWithDeadlines(d, context.Background(), service+"GetUnit")
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(2*time.Second), deadline, 100*time.Millisecond)
+
+	// no limits at all
+	_, ok = callWithDeadlines(d, context.Background(), service+"Delete")
+	require.False(t, ok)
+}
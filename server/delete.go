@@ -2,10 +2,8 @@ package server
 
 import (
 	"context"
-	"errors"
 
 	"github.com/AltMax/art-test/services"
-	"github.com/AltMax/art-test/units/dao"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -16,9 +14,6 @@ func (h *UnitService) Delete(ctx context.Context, req *services.DeleteUnitReques
 	}
 
 	err := h.units.Delete(ctx, req.Id)
-	if errors.Is(err, dao.ErrNotFound) {
-		return nil, status.Error(codes.NotFound, "unit not found")
-	}
 	if err != nil {
 		return nil, err
 	}
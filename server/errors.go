@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AltMax/art-test/units"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	errorDomain = "unit.art.test"
+
+	unavailableRetryDelay = time.Second
+)
+
+type errorMapping struct {
+	kind    error
+	code    codes.Code
+	reason  string
+	message string
+}
+
+// errorMappings is checked in order, the first matching kind wins.
+var errorMappings = []errorMapping{
+	{units.ErrNotFound, codes.NotFound, "UNIT_NOT_FOUND", "unit not found"},
+	{units.ErrPreconditionFailed, codes.FailedPrecondition, "PRECONDITION_FAILED", "precondition failed"},
+	{units.ErrUnavailable, codes.Unavailable, "STORAGE_UNAVAILABLE", "service temporarily unavailable"},
+	{units.ErrDeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED", "deadline exceeded"},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED", "deadline exceeded"},
+	{context.Canceled, codes.Canceled, "CANCELED", "request canceled"},
+}
+
+// errorToStatus converts err to a grpc status error. Errors that are already
+// statuses are returned unchanged, unknown errors become codes.Internal.
+// Messages never contain the original error text.
+func errorToStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	mapping := errorMapping{code: codes.Internal, reason: "INTERNAL", message: "internal error"}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.kind) {
+			mapping = m
+			break
+		}
+	}
+
+	details := []proto.Message{
+		&errdetails.ErrorInfo{Reason: mapping.reason, Domain: errorDomain},
+	}
+	if mapping.code == codes.Unavailable {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(unavailableRetryDelay)})
+	}
+
+	st := status.New(mapping.code, mapping.message)
+	if withDetails, detailsErr := st.WithDetails(details...); detailsErr == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/AltMax/art-test/services"
+	"github.com/AltMax/art-test/units"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_errorToStatus(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.1:5432: connect: connection refused")
+
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{fmt.Errorf("op, %w", units.ErrNotFound), codes.NotFound},
+		{units.WithKind(units.ErrPreconditionFailed, cause), codes.FailedPrecondition},
+		{units.WithKind(units.ErrUnavailable, cause), codes.Unavailable},
+		{units.WithKind(units.ErrDeadlineExceeded, cause), codes.DeadlineExceeded},
+		{fmt.Errorf("op, %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{cause, codes.Internal},
+		{status.Error(codes.InvalidArgument, "id is required"), codes.InvalidArgument},
+	}
+
+	for _, test := range tests {
+		st, ok := status.FromError(errorToStatus(test.err))
+		require.True(t, ok)
+		require.Equal(t, test.code, st.Code(), test.err.Error())
+		require.NotContains(t, st.Message(), cause.Error())
+	}
+
+	require.NoError(t, errorToStatus(nil))
+}
+
+func Test_ErrorToInternalErrorMiddleware_Unavailable(t *testing.T) {
+	handler := newTestHandler()
+	ctx := context.Background()
+
+	handler.unitsMock.On("FindByID", mock.Anything, "someID").
+		Return(nil, units.WithKind(units.ErrUnavailable, errors.New("connection refused")))
+
+	resp, err := handler.unitServiceClient.GetUnit(ctx, &services.GetUnitRequest{Id: "someID"})
+	require.Nil(t, resp)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Unavailable, st.Code())
+
+	var (
+		errorInfo *errdetails.ErrorInfo
+		retryInfo *errdetails.RetryInfo
+	)
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			errorInfo = d
+		case *errdetails.RetryInfo:
+			retryInfo = d
+		}
+	}
+	require.NotNil(t, errorInfo)
+	require.Equal(t, "STORAGE_UNAVAILABLE", errorInfo.Reason)
+	require.NotNil(t, retryInfo)
+	require.Equal(t, unavailableRetryDelay, retryInfo.RetryDelay.AsDuration())
+}
@@ -2,31 +2,229 @@ package server
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/services"
+	"github.com/AltMax/art-test/units"
 )
 
+var ErrSyncInProgress = errors.New("sync is already in progress")
+
+// SyncStatus describes the state of units synchronization with the database.
+type SyncStatus struct {
+	InProgress     bool
+	LastStartedAt  time.Time
+	LastFinishedAt time.Time
+	LastSuccessAt  time.Time
+	LastDuration   time.Duration
+	LastError      error
+	LastFailureAt  time.Time
+	// Failures is the number of failed full syncs since the last success.
+	Failures    int
+	NextSyncAt  time.Time
+	SyncedUnits int
+	// Watermark is the latest change applied to the layers,
+	// the next delta sync starts from it.
+	Watermark          time.Time
+	LastDeltaSuccessAt time.Time
+	LastDeltaError     error
+	// RestoredAt is when units were restored from a snapshot,
+	// delta syncs start from its watermark before the first full sync.
+	RestoredAt time.Time
+}
+
+func (s SyncStatus) Proto() *services.SyncStatus {
+	pb := &services.SyncStatus{
+		InProgress:     s.InProgress,
+		LastStartedAt:  timeToMilliseconds(s.LastStartedAt),
+		LastFinishedAt: timeToMilliseconds(s.LastFinishedAt),
+		LastSuccessAt:  timeToMilliseconds(s.LastSuccessAt),
+		LastDuration:   s.LastDuration.Milliseconds(),
+		LastFailureAt:  timeToMilliseconds(s.LastFailureAt),
+		Failures:       int64(s.Failures),
+		NextSyncAt:     timeToMilliseconds(s.NextSyncAt),
+		SyncedUnits:    int64(s.SyncedUnits),
+
+		Watermark:          timeToMilliseconds(s.Watermark),
+		LastDeltaSuccessAt: timeToMilliseconds(s.LastDeltaSuccessAt),
+		RestoredAt:         timeToMilliseconds(s.RestoredAt),
+	}
+	if s.LastError != nil {
+		pb.LastError = s.LastError.Error()
+	}
+	if s.LastDeltaError != nil {
+		pb.LastDeltaError = s.LastDeltaError.Error()
+	}
+	return pb
+}
+
+func timeToMilliseconds(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// FetchUnitsSometimes runs full syncs every fetchUnitsTimeout and delta syncs
+// every deltaSyncInterval until ctx is done. Failed syncs are retried with
+// backoff, the time of a sync is counted from the end of the previous one.
 func (h *UnitService) FetchUnitsSometimes(ctx context.Context) {
-	fetchTiker := time.NewTicker(h.fetchUnitsTimeout)
-	defer fetchTiker.Stop()
-
-	//чтобы сихронизация стора с базой не отъедала время тикера
-	resetTiker := func() {
-		fetchTiker.Stop()
-		fetchTiker = time.NewTicker(h.fetchUnitsTimeout)
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-fetchTiker.C:
-			_, err := h.units.FetchAll(ctx)
-			if err != nil {
-				log.Error().Err(err).Msg("fetch units")
-			}
-			resetTiker()
+	if h.deltaSync != nil {
+		go h.deltaSync.Run(ctx)
+	}
+	h.fullSync.Run(ctx)
+}
+
+// TriggerSync makes FetchUnitsSometimes run a full sync now.
+func (h *UnitService) TriggerSync() {
+	h.fullSync.Trigger()
+}
+
+// scheduledSync is Sync that skips the scheduled sync
+// when one was started by other means.
+func (h *UnitService) scheduledSync(ctx context.Context) error {
+	err := h.Sync(ctx)
+	if errors.Is(err, ErrSyncInProgress) {
+		return nil
+	}
+	return err
+}
+
+func (h *UnitService) scheduledSyncChanges(ctx context.Context) error {
+	err := h.SyncChanges(ctx)
+	if errors.Is(err, ErrSyncInProgress) {
+		return nil
+	}
+	return err
+}
+
+// Sync fetches all units from the database into the in-memory layers.
+// Only one sync runs at a time, ErrSyncInProgress is returned otherwise.
+func (h *UnitService) Sync(ctx context.Context) error {
+	if !h.startSync() {
+		return ErrSyncInProgress
+	}
+	return h.runSync(ctx)
+}
+
+// StartSync is Sync that runs in background, errors are logged.
+func (h *UnitService) StartSync(ctx context.Context) error {
+	if !h.startSync() {
+		return ErrSyncInProgress
+	}
+	go func() {
+		err := h.runSync(ctx)
+		if err != nil {
+			loggerFromContext(ctx).Error().Err(err).Msg("fetch units")
 		}
+	}()
+	return nil
+}
+
+func (h *UnitService) startSync() bool {
+	if !h.syncMu.TryLock() {
+		return false
+	}
+	h.updateSyncStatus(func(s *SyncStatus) {
+		s.InProgress = true
+		s.LastStartedAt = time.Now()
+	})
+	return true
+}
+
+func (h *UnitService) runSync(ctx context.Context) error {
+	defer h.syncMu.Unlock()
+
+	start := h.SyncStatus().LastStartedAt
+	// units are streamed through the layers, only their number
+	// and the latest write are kept
+	var (
+		synced    int
+		watermark time.Time
+	)
+	err := units.Scan(ctx, h.units, func(batch models.Units) error {
+		synced += len(batch)
+		watermark = latest(watermark, batch.LastUpdatedAt())
+		return nil
+	})
+
+	finish := time.Now()
+	h.updateSyncStatus(func(s *SyncStatus) {
+		s.InProgress = false
+		s.LastFinishedAt = finish
+		s.LastDuration = finish.Sub(start)
+		s.LastError = err
+		if err != nil {
+			s.LastFailureAt = finish
+			s.Failures++
+		} else {
+			s.Failures = 0
+			s.LastSuccessAt = finish
+			s.SyncedUnits = synced
+			s.Watermark = latest(s.Watermark, watermark)
+		}
+	})
+
+	return err
+}
+
+// Restored marks the layers as filled from a snapshot of units synced
+// up to watermark, so SyncChanges catches up from it.
+func (h *UnitService) Restored(watermark time.Time, units int) {
+	h.updateSyncStatus(func(s *SyncStatus) {
+		s.RestoredAt = time.Now()
+		s.SyncedUnits = units
+		s.Watermark = latest(s.Watermark, watermark)
+	})
+}
+
+// SyncChanges applies units changed since the watermark to the in-memory
+// layers. Nothing is done until the first full sync has succeeded
+// or a snapshot is restored.
+func (h *UnitService) SyncChanges(ctx context.Context) error {
+	if !h.deltaMu.TryLock() {
+		return ErrSyncInProgress
 	}
+	defer h.deltaMu.Unlock()
+
+	status := h.SyncStatus()
+	if status.LastSuccessAt.IsZero() && status.RestoredAt.IsZero() {
+		return nil
+	}
+
+	changes, err := h.units.FetchChanges(ctx, status.Watermark.Add(-h.deltaSyncOverlap))
+
+	finish := time.Now()
+	h.updateSyncStatus(func(s *SyncStatus) {
+		s.LastDeltaError = err
+		if err == nil {
+			s.LastDeltaSuccessAt = finish
+			s.Watermark = latest(s.Watermark, changes.Watermark)
+		}
+	})
+
+	return err
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func (h *UnitService) SyncStatus() SyncStatus {
+	h.syncStatusMu.RLock()
+	status := h.syncStatus
+	h.syncStatusMu.RUnlock()
+	status.NextSyncAt = h.fullSync.Status().NextRunAt
+	return status
+}
+
+func (h *UnitService) updateSyncStatus(update func(s *SyncStatus)) {
+	h.syncStatusMu.Lock()
+	defer h.syncStatusMu.Unlock()
+	update(&h.syncStatus)
 }
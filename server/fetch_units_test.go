@@ -1,12 +1,15 @@
 package server
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/scheduler"
 	"github.com/AltMax/art-test/units/mocks"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 )
 
@@ -26,3 +29,75 @@ func Test_FetchUnitsSometimes(t *testing.T) {
 
 	unitsMock.AssertNumberOfCalls(t, "FetchAll", 2)
 }
+
+func Test_FetchUnitsSometimes_Trigger(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	handler := NewUnitService(unitsMock, time.Hour, WithSchedule(scheduler.WithBackoff(time.Millisecond, time.Millisecond)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the failed sync is retried without waiting for the interval
+	unitsMock.On("FetchAll", mock.Anything).Return(nil, errors.New("db is down")).Once()
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{randomUnit()}, nil)
+
+	go handler.FetchUnitsSometimes(ctx)
+	handler.TriggerSync()
+
+	require.Eventually(t, func() bool {
+		return !handler.SyncStatus().LastSuccessAt.IsZero()
+	}, time.Second, time.Millisecond)
+	status := handler.SyncStatus()
+	require.Equal(t, 0, status.Failures)
+	require.False(t, status.LastFailureAt.IsZero())
+	unitsMock.AssertNumberOfCalls(t, "FetchAll", 2)
+}
+
+func Test_SyncChanges(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	handler := NewUnitService(unitsMock, time.Hour, WithDeltaSync(time.Second, time.Minute))
+
+	ctx := context.Background()
+
+	// nothing to do before the first full sync
+	err := handler.SyncChanges(ctx)
+	require.NoError(t, err)
+	unitsMock.AssertNotCalled(t, "FetchChanges", mock.Anything, mock.Anything)
+
+	unit := randomUnit()
+	unit.UpdatedAt = time.Now().UTC()
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{unit}, nil)
+	err = handler.Sync(ctx)
+	require.NoError(t, err)
+	require.Equal(t, unit.UpdatedAt, handler.SyncStatus().Watermark)
+
+	watermark := unit.UpdatedAt.Add(time.Second)
+	since := unit.UpdatedAt.Add(-time.Minute)
+	unitsMock.On("FetchChanges", mock.Anything, since).Return(&models.Changes{Watermark: watermark}, nil)
+	err = handler.SyncChanges(ctx)
+	require.NoError(t, err)
+
+	status := handler.SyncStatus()
+	require.Equal(t, watermark, status.Watermark)
+	require.False(t, status.LastDeltaSuccessAt.IsZero())
+	require.NoError(t, status.LastDeltaError)
+}
+
+func Test_SyncChanges_Restored(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	handler := NewUnitService(unitsMock, time.Hour, WithDeltaSync(time.Second, time.Minute))
+
+	watermark := time.Now().UTC()
+	handler.Restored(watermark, 10)
+
+	status := handler.SyncStatus()
+	require.Equal(t, watermark, status.Watermark)
+	require.Equal(t, 10, status.SyncedUnits)
+	require.False(t, status.RestoredAt.IsZero())
+
+	unitsMock.On("FetchChanges", mock.Anything, watermark.Add(-time.Minute)).
+		Return(&models.Changes{Watermark: watermark}, nil).Once()
+	err := handler.SyncChanges(context.Background())
+	require.NoError(t, err)
+	unitsMock.AssertExpectations(t)
+}
@@ -12,6 +12,9 @@ func (h *UnitService) GetUnits(ctx context.Context, req *services.GetUnitsReques
 	if len(req.Ids) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "at least one id is required")
 	}
+	if h.maxGetUnits > 0 && len(req.Ids) > h.maxGetUnits {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ids: %d, at most %d are allowed", len(req.Ids), h.maxGetUnits)
+	}
 
 	units, err := h.units.FindByIDs(ctx, req.Ids)
 	if err != nil {
@@ -24,6 +24,20 @@ func Test_GetUnits_Negative_MissingParameter(t *testing.T) {
 	require.Nil(t, resp)
 }
 
+func Test_GetUnits_Negative_TooManyIDs(t *testing.T) {
+	handler := newTestHandler(WithMaxGetUnits(2))
+	ctx := context.Background()
+
+	resp, err := handler.unitServiceClient.GetUnits(ctx, &services.GetUnitsRequest{Ids: []string{"1", "2", "3"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Nil(t, resp)
+	handler.unitsMock.AssertNotCalled(t, "FindByIDs", mock.Anything, mock.Anything)
+
+	handler.unitsMock.On("FindByIDs", mock.Anything, []string{"1", "2"}).Return(models.Units{}, nil)
+	_, err = handler.unitServiceClient.GetUnits(ctx, &services.GetUnitsRequest{Ids: []string{"1", "2"}})
+	require.NoError(t, err)
+}
+
 func Test_GetUnits_Positive_NotFound(t *testing.T) {
 	handler := newTestHandler()
 	ctx := context.Background()
@@ -1,19 +1,66 @@
 package server
 
 import (
+	"sync"
 	"time"
 
+	"github.com/AltMax/art-test/scheduler"
 	"github.com/AltMax/art-test/units"
 )
 
 type UnitService struct {
 	units             units.Units
 	fetchUnitsTimeout time.Duration
+	deltaSyncInterval time.Duration
+	deltaSyncOverlap  time.Duration
+	maxGetUnits       int
+	schedule          []scheduler.Option
+	fullSync          *scheduler.Job
+	deltaSync         *scheduler.Job
+
+	syncMu       sync.Mutex
+	deltaMu      sync.Mutex
+	syncStatusMu sync.RWMutex
+	syncStatus   SyncStatus
 }
 
-func NewUnitService(units units.Units, d time.Duration) *UnitService {
-	return &UnitService{
+type UnitServiceOption func(*UnitService)
+
+// WithDeltaSync makes FetchUnitsSometimes fetch only changed units every
+// interval between full syncs. overlap is subtracted from the watermark,
+// so writes committed late by long transactions are not missed.
+func WithDeltaSync(interval, overlap time.Duration) UnitServiceOption {
+	return func(h *UnitService) {
+		h.deltaSyncInterval = interval
+		h.deltaSyncOverlap = overlap
+	}
+}
+
+// WithMaxGetUnits limits the number of ids GetUnits accepts, 0 means no limit.
+func WithMaxGetUnits(n int) UnitServiceOption {
+	return func(h *UnitService) {
+		h.maxGetUnits = n
+	}
+}
+
+// WithSchedule sets jitter and backoff of syncs run by FetchUnitsSometimes.
+func WithSchedule(opts ...scheduler.Option) UnitServiceOption {
+	return func(h *UnitService) {
+		h.schedule = opts
+	}
+}
+
+func NewUnitService(units units.Units, d time.Duration, opts ...UnitServiceOption) *UnitService {
+	h := &UnitService{
 		units:             units,
 		fetchUnitsTimeout: d,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.fullSync = scheduler.New("sync", h.fetchUnitsTimeout, h.scheduledSync, h.schedule...)
+	if h.deltaSyncInterval > 0 {
+		h.deltaSync = scheduler.New("delta sync", h.deltaSyncInterval, h.scheduledSyncChanges, h.schedule...)
+	}
+	return h
 }
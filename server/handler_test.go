@@ -41,7 +41,7 @@ type handler struct {
 	unitServiceClient services.UnitServiceClient
 }
 
-func newTestHandler() *handler {
+func newTestHandler(opts ...UnitServiceOption) *handler {
 	conf, err := config.New()
 	if err != nil {
 		panic(err)
@@ -53,7 +53,7 @@ func newTestHandler() *handler {
 		unitsMock: unitsMock,
 	}
 
-	service := NewUnitService(unitsMock, 1*time.Second)
+	service := NewUnitService(unitsMock, 1*time.Second, opts...)
 	listener, conn := newMockGrpcConnAndListener()
 	srv := New(&conf)
 	services.RegisterUnitServiceServer(srv, service)
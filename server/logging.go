@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/AltMax/art-test/config"
+	"github.com/AltMax/art-test/services"
+	"github.com/google/uuid"
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const dataHashSize = 8
+
+type requestIDKey struct{}
+
+// requestIDFromContext returns the request id set by requestIDMiddleware.
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+type requestLogger struct {
+	logger          zerolog.Logger
+	level           zerolog.Level
+	methodLevels    map[string]zerolog.Level
+	sampleRate      float64
+	maxPayloadSize  int
+	requestIDHeader string
+}
+
+func newRequestLogger(conf config.Logging) *requestLogger {
+	// levels are validated by config.New
+	level, _ := zerolog.ParseLevel(conf.Level)
+	methodLevels := make(map[string]zerolog.Level, len(conf.Methods))
+	for method, methodLevel := range conf.Methods {
+		methodLevels[strings.ToLower(method)], _ = zerolog.ParseLevel(methodLevel)
+	}
+	return &requestLogger{
+		logger:          log.Logger,
+		level:           level,
+		methodLevels:    methodLevels,
+		sampleRate:      conf.SampleRate,
+		maxPayloadSize:  conf.MaxPayloadSize,
+		requestIDHeader: strings.ToLower(conf.RequestIDHeader),
+	}
+}
+
+func (l *requestLogger) levelFor(fullMethod string) zerolog.Level {
+	if level, ok := l.methodLevels[strings.ToLower(fullMethod)]; ok {
+		return level
+	}
+	if level, ok := l.methodLevels[strings.ToLower(path.Base(fullMethod))]; ok {
+		return level
+	}
+	return l.level
+}
+
+// requestIDMiddleware takes the request id from incoming metadata or generates
+// a new one, sends it back in the response header and puts a logger with it
+// into the context, so zerolog.Ctx(ctx) logs it in every line.
+func (l *requestLogger) requestIDMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(l.withRequestID(ctx), req)
+}
+
+func (l *requestLogger) requestIDStreamMiddleware(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapped := grpcMiddleware.WrapServerStream(stream)
+	wrapped.WrappedContext = l.withRequestID(stream.Context())
+	return handler(srv, wrapped)
+}
+
+func (l *requestLogger) withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(l.requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(l.requestIDHeader, requestID))
+
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	logger := l.logger.With().Str("request_id", requestID).Logger()
+	return logger.WithContext(ctx)
+}
+
+func (l *requestLogger) logIncomingRequestsMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	level := l.levelFor(info.FullMethod)
+	start := time.Now()
+	result, err := handler(ctx, req)
+
+	logEvent := l.event(ctx, level, err)
+	if logEvent == nil {
+		return result, err
+	}
+	if err == nil {
+		logEvent.Object("response", payload{msg: result, maxSize: l.maxPayloadSize})
+	}
+	logEvent.
+		Dur("duration", time.Since(start)).
+		Object("request", payload{msg: req, maxSize: l.maxPayloadSize}).
+		Str("url", info.FullMethod).
+		Msg("complete")
+
+	return result, err
+}
+
+// logIncomingStreamsMiddleware logs streaming calls once they are finished.
+// Messages are only counted, their content is never logged.
+func (l *requestLogger) logIncomingStreamsMiddleware(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	level := l.levelFor(info.FullMethod)
+	start := time.Now()
+	counted := &countingServerStream{ServerStream: stream}
+	err := handler(srv, counted)
+
+	logEvent := l.event(stream.Context(), level, err)
+	if logEvent == nil {
+		return err
+	}
+	logEvent.
+		Dur("duration", time.Since(start)).
+		Int("received", counted.received).
+		Int("sent", counted.sent).
+		Bool("client_stream", info.IsClientStream).
+		Bool("server_stream", info.IsServerStream).
+		Str("url", info.FullMethod).
+		Msg("complete")
+
+	return err
+}
+
+type countingServerStream struct {
+	grpc.ServerStream
+	received int
+	sent     int
+}
+
+func (s *countingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+	return err
+}
+
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+	return err
+}
+
+// loggerFromContext returns the logger of the request with its request id,
+// the global logger is used outside of requests.
+func loggerFromContext(ctx context.Context) *zerolog.Logger {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return &log.Logger
+	}
+	return logger
+}
+
+// event returns nil when the call should not be logged,
+// so nothing is marshalled in that case. Failed calls are logged
+// with error level whatever the level of the method is.
+func (l *requestLogger) event(ctx context.Context, level zerolog.Level, err error) *zerolog.Event {
+	var e *zerolog.Event
+	switch {
+	case err != nil:
+		e = l.logger.Error().Str("error", fmt.Sprintf("%+v", err))
+	case level == zerolog.Disabled:
+		return nil
+	case l.sampleRate < 1 && rand.Float64() >= l.sampleRate:
+		return nil
+	default:
+		e = l.logger.WithLevel(level)
+	}
+	if requestID := requestIDFromContext(ctx); requestID != "" {
+		e.Str("request_id", requestID)
+	}
+	return e
+}
+
+// payload logs a grpc message without its unit data, only the size and the hash
+// of data are logged. Messages of unknown shape are logged as truncated text.
+type payload struct {
+	msg     interface{}
+	maxSize int
+}
+
+func (p payload) MarshalZerologObject(e *zerolog.Event) {
+	if p.msg == nil {
+		return
+	}
+
+	known := false
+	if m, ok := p.msg.(interface{ GetId() string }); ok {
+		known = true
+		e.Str("id", m.GetId())
+	}
+	if m, ok := p.msg.(interface{ GetData() []byte }); ok {
+		known = true
+		data := m.GetData()
+		hash := sha256.Sum256(data)
+		e.Int("data_size", len(data)).Str("data_hash", hex.EncodeToString(hash[:dataHashSize]))
+	}
+	if m, ok := p.msg.(interface{ GetIds() []string }); ok {
+		known = true
+		ids := m.GetIds()
+		e.Int("ids_count", len(ids)).Strs("ids", p.truncateIDs(ids))
+	}
+	if m, ok := p.msg.(interface{ GetUnits() []*services.Unit }); ok {
+		known = true
+		units := m.GetUnits()
+		ids := make([]string, 0, len(units))
+		for _, unit := range units {
+			ids = append(ids, unit.GetId())
+		}
+		e.Int("units_count", len(units)).Strs("ids", p.truncateIDs(ids))
+	}
+
+	if !known {
+		e.Str("text", p.truncate(fmt.Sprintf("%v", p.msg)))
+	}
+}
+
+func (p payload) truncateIDs(ids []string) []string {
+	size := 0
+	for i, id := range ids {
+		size += len(id)
+		if size > p.maxSize {
+			return ids[:i]
+		}
+	}
+	return ids
+}
+
+func (p payload) truncate(s string) string {
+	if len(s) <= p.maxSize {
+		return s
+	}
+	return s[:p.maxSize] + "..."
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AltMax/art-test/config"
+	"github.com/AltMax/art-test/services"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func newTestRequestLogger(conf config.Logging) (*requestLogger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l := newRequestLogger(conf)
+	l.logger = zerolog.New(buf)
+	return l, buf
+}
+
+func testLoggingConfig() config.Logging {
+	return config.Logging{
+		Level:           "info",
+		SampleRate:      1,
+		MaxPayloadSize:  64,
+		RequestIDHeader: "x-request-id",
+	}
+}
+
+func callLogged(l *requestLogger, ctx context.Context, method string, req, resp interface{}, err error) {
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return resp, err
+	}
+	_, _ = l.requestIDMiddleware(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return l.logIncomingRequestsMiddleware(ctx, req, info, handler)
+	})
+}
+
+func Test_logIncomingRequestsMiddleware_RedactsData(t *testing.T) {
+	l, buf := newTestRequestLogger(testLoggingConfig())
+
+	unit := randomUnit()
+	unit.Data = []byte("very secret payload")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-1"))
+
+	callLogged(l, ctx, "/test.art.unit.UnitService/Create", &services.CreateUnitRequest{Data: unit.Data}, unit.Proto(), nil)
+
+	line := buf.String()
+	require.NotContains(t, line, "very secret payload")
+	require.Contains(t, line, `"data_size":19`)
+	require.Contains(t, line, `"data_hash"`)
+	require.Contains(t, line, `"request_id":"req-1"`)
+	require.Contains(t, line, unit.ID)
+}
+
+func Test_logIncomingRequestsMiddleware_TruncatesIDs(t *testing.T) {
+	l, buf := newTestRequestLogger(testLoggingConfig())
+
+	ids := []string{randomUnit().ID, randomUnit().ID, randomUnit().ID}
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/GetUnits", &services.GetUnitsRequest{Ids: ids}, nil, nil)
+
+	line := buf.String()
+	require.Contains(t, line, `"ids_count":3`)
+	require.Contains(t, line, ids[0])
+	require.NotContains(t, line, ids[2])
+}
+
+func Test_logIncomingRequestsMiddleware_MethodLevels(t *testing.T) {
+	conf := testLoggingConfig()
+	conf.Methods = map[string]string{"getunit": "disabled", "/test.art.unit.UnitService/Update": "debug"}
+	l, buf := newTestRequestLogger(conf)
+
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/GetUnit", &services.GetUnitRequest{Id: "id"}, nil, nil)
+	require.Empty(t, buf.String())
+
+	// failed calls are logged even when the method is disabled
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/GetUnit", &services.GetUnitRequest{Id: "id"}, nil, errors.New("failed"))
+	require.Contains(t, buf.String(), `"level":"error"`)
+	require.Contains(t, buf.String(), `"request_id"`)
+	buf.Reset()
+
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/Update", &services.UpdateUnitRequest{Id: "id"}, nil, nil)
+	require.Contains(t, buf.String(), `"level":"debug"`)
+}
+
+func Test_logIncomingRequestsMiddleware_Sampling(t *testing.T) {
+	conf := testLoggingConfig()
+	conf.SampleRate = 0
+	l, buf := newTestRequestLogger(conf)
+
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/GetUnit", &services.GetUnitRequest{Id: "id"}, nil, nil)
+	require.Empty(t, buf.String())
+
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/GetUnit", &services.GetUnitRequest{Id: "id"}, nil, errors.New("failed"))
+	require.Contains(t, buf.String(), `"level":"error"`)
+}
+
+func Test_requestIDMiddleware_Generates(t *testing.T) {
+	l, buf := newTestRequestLogger(testLoggingConfig())
+
+	callLogged(l, context.Background(), "/test.art.unit.UnitService/GetUnit", &services.GetUnitRequest{Id: "id"}, nil, nil)
+	require.Regexp(t, `"request_id":"[0-9a-f-]{36}"`, buf.String())
+}
@@ -2,25 +2,18 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/AltMax/art-test/config"
 	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpcRecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	grpcValidator "github.com/grpc-ecosystem/go-grpc-middleware/validator"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func recoveryHandler(ctx context.Context, p interface{}) (err error) {
-	log.Error().
+	loggerFromContext(ctx).Error().
 		Str("panic", fmt.Sprintf("%+v", p)).
-		Str("ctx", fmt.Sprintf("%+v", ctx)).
 		Msg("PANIC")
 	if err, ok := p.(error); ok {
 		return fmt.Errorf("panic: %w", err)
@@ -28,51 +21,54 @@ func recoveryHandler(ctx context.Context, p interface{}) (err error) {
 	return fmt.Errorf("panic: %+v", p)
 }
 
-// ErrorToInternalErrorMiddleware converts errors to google grpc errors with code Internsl
+// ErrorToInternalErrorMiddleware converts errors to google grpc errors.
+// Domain errors from the units package get their own codes and details,
+// everything else becomes codes.Internal.
 func ErrorToInternalErrorMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (result interface{}, err error) {
 
 	result, err = handler(ctx, req)
 
-	_, ok := status.FromError(err)
-	if ok {
-		return
-	}
-
-	err = status.Error(codes.Internal, "")
+	err = errorToStatus(err)
 
 	return
 }
 
+// ErrorToInternalErrorStreamMiddleware is ErrorToInternalErrorMiddleware for streaming calls.
+func ErrorToInternalErrorStreamMiddleware(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return errorToStatus(handler(srv, stream))
+}
+
 func New(conf *config.Config, middlewares ...grpc.UnaryServerInterceptor) *grpc.Server {
+	return NewWithStreamMiddlewares(conf, nil, middlewares...)
+}
+
+// NewWithStreamMiddlewares is New that also appends streamMiddlewares
+// to the chain of stream interceptors.
+func NewWithStreamMiddlewares(conf *config.Config, streamMiddlewares []grpc.StreamServerInterceptor, middlewares ...grpc.UnaryServerInterceptor) *grpc.Server {
+	requestLogger := newRequestLogger(conf.Logging)
+	deadlines := newDeadlines(conf.Deadlines)
 	interceptors := []grpc.UnaryServerInterceptor{
-		grpcRecovery.UnaryServerInterceptor(grpcRecovery.WithRecoveryHandlerContext(recoveryHandler)),
+		requestLogger.requestIDMiddleware,
 		ErrorToInternalErrorMiddleware,
-		logIncomingRequestsMiddleware,
+		grpcRecovery.UnaryServerInterceptor(grpcRecovery.WithRecoveryHandlerContext(recoveryHandler)),
+		requestLogger.logIncomingRequestsMiddleware,
+		deadlines.deadlineMiddleware,
 		grpcValidator.UnaryServerInterceptor(),
 	}
 
 	interceptors = append(interceptors, middlewares...)
-	return grpc.NewServer(grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(interceptors...)))
-}
 
-func logIncomingRequestsMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	start := time.Now()
-	requestJSON, _ := json.Marshal(req)
-	result, err := handler(ctx, req)
-	responseJSON, _ := json.Marshal(result)
-	var logEvent *zerolog.Event
-	if err != nil {
-		logEvent = log.Error().Str("error", fmt.Sprintf("%+v", err))
-	} else {
-		logEvent = log.Info()
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		requestLogger.requestIDStreamMiddleware,
+		ErrorToInternalErrorStreamMiddleware,
+		grpcRecovery.StreamServerInterceptor(grpcRecovery.WithRecoveryHandlerContext(recoveryHandler)),
+		requestLogger.logIncomingStreamsMiddleware,
+		grpcValidator.StreamServerInterceptor(),
 	}
-	logEvent.
-		Dur("duration", time.Since(start)).
-		RawJSON("json_response", responseJSON).
-		RawJSON("json_request", requestJSON).
-		Str("url", info.FullMethod).
-		Str("ctx", fmt.Sprintf("%+v", ctx)).
-		Msg("complete")
+	streamInterceptors = append(streamInterceptors, streamMiddlewares...)
 
-	return result, err
+	return grpc.NewServer(
+		grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(interceptors...)),
+		grpc.StreamInterceptor(grpcMiddleware.ChainStreamServer(streamInterceptors...)),
+	)
 }
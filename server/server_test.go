@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/AltMax/art-test/config"
+	"github.com/AltMax/art-test/services"
+	"github.com/AltMax/art-test/units"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testStreamService interface {
+	Echo(stream grpc.ServerStream) error
+}
+
+type testStreamServer struct {
+	echo func(stream grpc.ServerStream) error
+}
+
+func (s *testStreamServer) Echo(stream grpc.ServerStream) error {
+	return s.echo(stream)
+}
+
+var testStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.art.unit.TestStreamService",
+	HandlerType: (*testStreamService)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName: "Echo",
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			return srv.(testStreamService).Echo(stream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+func newTestStreamClient(t *testing.T, echo func(stream grpc.ServerStream) error, streamMiddlewares ...grpc.StreamServerInterceptor) grpc.ClientStream {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	listener, conn := newMockGrpcConnAndListener()
+	srv := NewWithStreamMiddlewares(&conf, streamMiddlewares)
+	srv.RegisterService(&testStreamServiceDesc, &testStreamServer{echo: echo})
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	stream, err := conn.NewStream(context.Background(), &testStreamServiceDesc.Streams[0], "/test.art.unit.TestStreamService/Echo")
+	require.NoError(t, err)
+	return stream
+}
+
+func echoAll(stream grpc.ServerStream) error {
+	for {
+		msg := &services.Empty{}
+		err := stream.RecvMsg(msg)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func Test_StreamInterceptors_Positive(t *testing.T) {
+	calls := 0
+	middleware := func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		calls++
+		return handler(srv, stream)
+	}
+
+	stream := newTestStreamClient(t, echoAll, middleware)
+	require.NoError(t, stream.SendMsg(&services.Empty{}))
+	require.NoError(t, stream.CloseSend())
+	require.NoError(t, stream.RecvMsg(&services.Empty{}))
+	require.ErrorIs(t, stream.RecvMsg(&services.Empty{}), io.EOF)
+	require.Equal(t, 1, calls)
+}
+
+func Test_StreamInterceptors_Panic(t *testing.T) {
+	stream := newTestStreamClient(t, func(stream grpc.ServerStream) error {
+		panic("boom")
+	})
+
+	err := stream.RecvMsg(&services.Empty{})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Internal, st.Code())
+	require.NotContains(t, st.Message(), "boom")
+}
+
+func Test_StreamInterceptors_ErrorMapping(t *testing.T) {
+	stream := newTestStreamClient(t, func(stream grpc.ServerStream) error {
+		return units.ErrNotFound
+	})
+
+	err := stream.RecvMsg(&services.Empty{})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.NotFound, st.Code())
+}
+
+func Test_UnaryInterceptors_Panic(t *testing.T) {
+	handler := newTestHandler()
+	ctx := context.Background()
+
+	handler.unitsMock.On("FindByID", mock.Anything, "panicID").Run(func(mock.Arguments) {
+		panic("boom")
+	})
+
+	resp, err := handler.unitServiceClient.GetUnit(ctx, &services.GetUnitRequest{Id: "panicID"})
+	require.Nil(t, resp)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Internal, st.Code())
+	require.NotContains(t, st.Message(), "boom")
+}
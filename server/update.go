@@ -2,10 +2,8 @@ package server
 
 import (
 	"context"
-	"errors"
 
 	"github.com/AltMax/art-test/services"
-	"github.com/AltMax/art-test/units/dao"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -19,9 +17,6 @@ func (h *UnitService) Update(ctx context.Context, req *services.UpdateUnitReques
 	}
 
 	unit, err := h.units.Update(ctx, req.Id, req.Data)
-	if errors.Is(err, dao.ErrNotFound) {
-		return nil, status.Error(codes.NotFound, "unit not found")
-	}
 	if err != nil {
 		return nil, err
 	}
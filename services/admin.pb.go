@@ -0,0 +1,2902 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: admin.proto
+
+package services
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type InvalidateRequest struct {
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	// empty means all layers
+	Layers []string `protobuf:"bytes,2,rep,name=layers,proto3" json:"layers,omitempty"`
+}
+
+func (m *InvalidateRequest) Reset()         { *m = InvalidateRequest{} }
+func (m *InvalidateRequest) String() string { return proto.CompactTextString(m) }
+func (*InvalidateRequest) ProtoMessage()    {}
+func (*InvalidateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{0}
+}
+func (m *InvalidateRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *InvalidateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_InvalidateRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *InvalidateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvalidateRequest.Merge(m, src)
+}
+func (m *InvalidateRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *InvalidateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvalidateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvalidateRequest proto.InternalMessageInfo
+
+func (m *InvalidateRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *InvalidateRequest) GetLayers() []string {
+	if m != nil {
+		return m.Layers
+	}
+	return nil
+}
+
+type InvalidateAllRequest struct {
+	// empty means all layers
+	Layers []string `protobuf:"bytes,1,rep,name=layers,proto3" json:"layers,omitempty"`
+}
+
+func (m *InvalidateAllRequest) Reset()         { *m = InvalidateAllRequest{} }
+func (m *InvalidateAllRequest) String() string { return proto.CompactTextString(m) }
+func (*InvalidateAllRequest) ProtoMessage()    {}
+func (*InvalidateAllRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{1}
+}
+func (m *InvalidateAllRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *InvalidateAllRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_InvalidateAllRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *InvalidateAllRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvalidateAllRequest.Merge(m, src)
+}
+func (m *InvalidateAllRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *InvalidateAllRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvalidateAllRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvalidateAllRequest proto.InternalMessageInfo
+
+func (m *InvalidateAllRequest) GetLayers() []string {
+	if m != nil {
+		return m.Layers
+	}
+	return nil
+}
+
+type SyncStatus struct {
+	InProgress     bool   `protobuf:"varint,1,opt,name=in_progress,json=inProgress,proto3" json:"in_progress,omitempty"`
+	LastStartedAt  int64  `protobuf:"varint,2,opt,name=last_started_at,json=lastStartedAt,proto3" json:"last_started_at,omitempty"`
+	LastFinishedAt int64  `protobuf:"varint,3,opt,name=last_finished_at,json=lastFinishedAt,proto3" json:"last_finished_at,omitempty"`
+	LastSuccessAt  int64  `protobuf:"varint,4,opt,name=last_success_at,json=lastSuccessAt,proto3" json:"last_success_at,omitempty"`
+	LastDuration   int64  `protobuf:"varint,5,opt,name=last_duration,json=lastDuration,proto3" json:"last_duration,omitempty"`
+	LastError      string `protobuf:"bytes,6,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	SyncedUnits    int64  `protobuf:"varint,7,opt,name=synced_units,json=syncedUnits,proto3" json:"synced_units,omitempty"`
+	// latest change applied, delta syncs start from it
+	Watermark          int64  `protobuf:"varint,8,opt,name=watermark,proto3" json:"watermark,omitempty"`
+	LastDeltaSuccessAt int64  `protobuf:"varint,9,opt,name=last_delta_success_at,json=lastDeltaSuccessAt,proto3" json:"last_delta_success_at,omitempty"`
+	LastDeltaError     string `protobuf:"bytes,10,opt,name=last_delta_error,json=lastDeltaError,proto3" json:"last_delta_error,omitempty"`
+	// when units were restored from a snapshot at startup
+	RestoredAt    int64 `protobuf:"varint,11,opt,name=restored_at,json=restoredAt,proto3" json:"restored_at,omitempty"`
+	LastFailureAt int64 `protobuf:"varint,12,opt,name=last_failure_at,json=lastFailureAt,proto3" json:"last_failure_at,omitempty"`
+	// failed full syncs since the last success
+	Failures int64 `protobuf:"varint,13,opt,name=failures,proto3" json:"failures,omitempty"`
+	// when the next scheduled full sync starts
+	NextSyncAt int64 `protobuf:"varint,14,opt,name=next_sync_at,json=nextSyncAt,proto3" json:"next_sync_at,omitempty"`
+}
+
+func (m *SyncStatus) Reset()         { *m = SyncStatus{} }
+func (m *SyncStatus) String() string { return proto.CompactTextString(m) }
+func (*SyncStatus) ProtoMessage()    {}
+func (*SyncStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{2}
+}
+func (m *SyncStatus) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SyncStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SyncStatus.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SyncStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncStatus.Merge(m, src)
+}
+func (m *SyncStatus) XXX_Size() int {
+	return m.Size()
+}
+func (m *SyncStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncStatus proto.InternalMessageInfo
+
+func (m *SyncStatus) GetInProgress() bool {
+	if m != nil {
+		return m.InProgress
+	}
+	return false
+}
+
+func (m *SyncStatus) GetLastStartedAt() int64 {
+	if m != nil {
+		return m.LastStartedAt
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastFinishedAt() int64 {
+	if m != nil {
+		return m.LastFinishedAt
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastSuccessAt() int64 {
+	if m != nil {
+		return m.LastSuccessAt
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastDuration() int64 {
+	if m != nil {
+		return m.LastDuration
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+func (m *SyncStatus) GetSyncedUnits() int64 {
+	if m != nil {
+		return m.SyncedUnits
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetWatermark() int64 {
+	if m != nil {
+		return m.Watermark
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastDeltaSuccessAt() int64 {
+	if m != nil {
+		return m.LastDeltaSuccessAt
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastDeltaError() string {
+	if m != nil {
+		return m.LastDeltaError
+	}
+	return ""
+}
+
+func (m *SyncStatus) GetRestoredAt() int64 {
+	if m != nil {
+		return m.RestoredAt
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetLastFailureAt() int64 {
+	if m != nil {
+		return m.LastFailureAt
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetFailures() int64 {
+	if m != nil {
+		return m.Failures
+	}
+	return 0
+}
+
+func (m *SyncStatus) GetNextSyncAt() int64 {
+	if m != nil {
+		return m.NextSyncAt
+	}
+	return 0
+}
+
+type LayerSize struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Entries int64  `protobuf:"varint,2,opt,name=entries,proto3" json:"entries,omitempty"`
+	// memory accounted by the layer, 0 if it doesn't account memory
+	Bytes int64 `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// ids remembered as missing and lookups answered by them
+	NegativeEntries int64  `protobuf:"varint,4,opt,name=negative_entries,json=negativeEntries,proto3" json:"negative_entries,omitempty"`
+	NegativeHits    uint64 `protobuf:"varint,5,opt,name=negative_hits,json=negativeHits,proto3" json:"negative_hits,omitempty"`
+	NegativeMisses  uint64 `protobuf:"varint,6,opt,name=negative_misses,json=negativeMisses,proto3" json:"negative_misses,omitempty"`
+}
+
+func (m *LayerSize) Reset()         { *m = LayerSize{} }
+func (m *LayerSize) String() string { return proto.CompactTextString(m) }
+func (*LayerSize) ProtoMessage()    {}
+func (*LayerSize) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{3}
+}
+func (m *LayerSize) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *LayerSize) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_LayerSize.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *LayerSize) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LayerSize.Merge(m, src)
+}
+func (m *LayerSize) XXX_Size() int {
+	return m.Size()
+}
+func (m *LayerSize) XXX_DiscardUnknown() {
+	xxx_messageInfo_LayerSize.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LayerSize proto.InternalMessageInfo
+
+func (m *LayerSize) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LayerSize) GetEntries() int64 {
+	if m != nil {
+		return m.Entries
+	}
+	return 0
+}
+
+func (m *LayerSize) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *LayerSize) GetNegativeEntries() int64 {
+	if m != nil {
+		return m.NegativeEntries
+	}
+	return 0
+}
+
+func (m *LayerSize) GetNegativeHits() uint64 {
+	if m != nil {
+		return m.NegativeHits
+	}
+	return 0
+}
+
+func (m *LayerSize) GetNegativeMisses() uint64 {
+	if m != nil {
+		return m.NegativeMisses
+	}
+	return 0
+}
+
+type GetLayerSizesResponse struct {
+	Layers []*LayerSize `protobuf:"bytes,1,rep,name=layers,proto3" json:"layers,omitempty"`
+}
+
+func (m *GetLayerSizesResponse) Reset()         { *m = GetLayerSizesResponse{} }
+func (m *GetLayerSizesResponse) String() string { return proto.CompactTextString(m) }
+func (*GetLayerSizesResponse) ProtoMessage()    {}
+func (*GetLayerSizesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{4}
+}
+func (m *GetLayerSizesResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GetLayerSizesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GetLayerSizesResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GetLayerSizesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLayerSizesResponse.Merge(m, src)
+}
+func (m *GetLayerSizesResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *GetLayerSizesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLayerSizesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLayerSizesResponse proto.InternalMessageInfo
+
+func (m *GetLayerSizesResponse) GetLayers() []*LayerSize {
+	if m != nil {
+		return m.Layers
+	}
+	return nil
+}
+
+type MethodStats struct {
+	Method string `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Calls  uint64 `protobuf:"varint,2,opt,name=calls,proto3" json:"calls,omitempty"`
+	// errors besides not found
+	Errors   uint64 `protobuf:"varint,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	NotFound uint64 `protobuf:"varint,4,opt,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
+	// ids requested and units returned by FindByIDs,
+	// units returned by FetchAll and FetchChanges
+	Requested uint64 `protobuf:"varint,5,opt,name=requested,proto3" json:"requested,omitempty"`
+	Returned  uint64 `protobuf:"varint,6,opt,name=returned,proto3" json:"returned,omitempty"`
+	// latencies in microseconds, p50 and p99 are upper bounds of histogram buckets
+	TotalLatency int64 `protobuf:"varint,7,opt,name=total_latency,json=totalLatency,proto3" json:"total_latency,omitempty"`
+	P50Latency   int64 `protobuf:"varint,8,opt,name=p50_latency,json=p50Latency,proto3" json:"p50_latency,omitempty"`
+	P99Latency   int64 `protobuf:"varint,9,opt,name=p99_latency,json=p99Latency,proto3" json:"p99_latency,omitempty"`
+}
+
+func (m *MethodStats) Reset()         { *m = MethodStats{} }
+func (m *MethodStats) String() string { return proto.CompactTextString(m) }
+func (*MethodStats) ProtoMessage()    {}
+func (*MethodStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{5}
+}
+func (m *MethodStats) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MethodStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MethodStats.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MethodStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MethodStats.Merge(m, src)
+}
+func (m *MethodStats) XXX_Size() int {
+	return m.Size()
+}
+func (m *MethodStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_MethodStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MethodStats proto.InternalMessageInfo
+
+func (m *MethodStats) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *MethodStats) GetCalls() uint64 {
+	if m != nil {
+		return m.Calls
+	}
+	return 0
+}
+
+func (m *MethodStats) GetErrors() uint64 {
+	if m != nil {
+		return m.Errors
+	}
+	return 0
+}
+
+func (m *MethodStats) GetNotFound() uint64 {
+	if m != nil {
+		return m.NotFound
+	}
+	return 0
+}
+
+func (m *MethodStats) GetRequested() uint64 {
+	if m != nil {
+		return m.Requested
+	}
+	return 0
+}
+
+func (m *MethodStats) GetReturned() uint64 {
+	if m != nil {
+		return m.Returned
+	}
+	return 0
+}
+
+func (m *MethodStats) GetTotalLatency() int64 {
+	if m != nil {
+		return m.TotalLatency
+	}
+	return 0
+}
+
+func (m *MethodStats) GetP50Latency() int64 {
+	if m != nil {
+		return m.P50Latency
+	}
+	return 0
+}
+
+func (m *MethodStats) GetP99Latency() int64 {
+	if m != nil {
+		return m.P99Latency
+	}
+	return 0
+}
+
+type LayerStats struct {
+	Name    string         `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Methods []*MethodStats `protobuf:"bytes,2,rep,name=methods,proto3" json:"methods,omitempty"`
+}
+
+func (m *LayerStats) Reset()         { *m = LayerStats{} }
+func (m *LayerStats) String() string { return proto.CompactTextString(m) }
+func (*LayerStats) ProtoMessage()    {}
+func (*LayerStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{6}
+}
+func (m *LayerStats) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *LayerStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_LayerStats.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *LayerStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LayerStats.Merge(m, src)
+}
+func (m *LayerStats) XXX_Size() int {
+	return m.Size()
+}
+func (m *LayerStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_LayerStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LayerStats proto.InternalMessageInfo
+
+func (m *LayerStats) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LayerStats) GetMethods() []*MethodStats {
+	if m != nil {
+		return m.Methods
+	}
+	return nil
+}
+
+type GetLayerStatsResponse struct {
+	// from the innermost layer
+	Layers []*LayerStats `protobuf:"bytes,1,rep,name=layers,proto3" json:"layers,omitempty"`
+}
+
+func (m *GetLayerStatsResponse) Reset()         { *m = GetLayerStatsResponse{} }
+func (m *GetLayerStatsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetLayerStatsResponse) ProtoMessage()    {}
+func (*GetLayerStatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{7}
+}
+func (m *GetLayerStatsResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GetLayerStatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GetLayerStatsResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GetLayerStatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLayerStatsResponse.Merge(m, src)
+}
+func (m *GetLayerStatsResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *GetLayerStatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLayerStatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLayerStatsResponse proto.InternalMessageInfo
+
+func (m *GetLayerStatsResponse) GetLayers() []*LayerStats {
+	if m != nil {
+		return m.Layers
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*InvalidateRequest)(nil), "test.art.unit.InvalidateRequest")
+	proto.RegisterType((*InvalidateAllRequest)(nil), "test.art.unit.InvalidateAllRequest")
+	proto.RegisterType((*SyncStatus)(nil), "test.art.unit.SyncStatus")
+	proto.RegisterType((*LayerSize)(nil), "test.art.unit.LayerSize")
+	proto.RegisterType((*GetLayerSizesResponse)(nil), "test.art.unit.GetLayerSizesResponse")
+	proto.RegisterType((*MethodStats)(nil), "test.art.unit.MethodStats")
+	proto.RegisterType((*LayerStats)(nil), "test.art.unit.LayerStats")
+	proto.RegisterType((*GetLayerStatsResponse)(nil), "test.art.unit.GetLayerStatsResponse")
+}
+
+func init() { proto.RegisterFile("admin.proto", fileDescriptor_73a7fc70dcc2027c) }
+
+var fileDescriptor_73a7fc70dcc2027c = []byte{
+	// 832 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x5d, 0x6f, 0x1b, 0x45,
+	0x14, 0xcd, 0xc6, 0x6e, 0xe2, 0xbd, 0x6b, 0xa7, 0x61, 0x94, 0xa2, 0xc5, 0x80, 0x6b, 0x1c, 0x04,
+	0xe6, 0xc5, 0x4a, 0x03, 0x7d, 0xf0, 0x03, 0x12, 0x46, 0x4d, 0x4a, 0x4b, 0x2b, 0xa1, 0xb5, 0xe0,
+	0x81, 0x97, 0xd5, 0xd4, 0x7b, 0xd3, 0xac, 0x58, 0xcf, 0x9a, 0x99, 0xbb, 0x01, 0xf3, 0x2b, 0xca,
+	0xaf, 0xe1, 0x2f, 0xf4, 0xb1, 0x8f, 0x3c, 0xa2, 0xe4, 0x8f, 0xa0, 0xf9, 0xd8, 0x8f, 0xb8, 0xc9,
+	0x43, 0x5e, 0xa2, 0xbd, 0x67, 0xce, 0x9c, 0xcc, 0x9c, 0x73, 0xef, 0x18, 0x02, 0x9e, 0x2c, 0x53,
+	0x31, 0x59, 0xc9, 0x9c, 0x72, 0xd6, 0x23, 0x54, 0x34, 0xe1, 0x92, 0x26, 0x85, 0x48, 0xa9, 0x0f,
+	0xfa, 0xaf, 0x5d, 0x1a, 0x7d, 0x0b, 0x1f, 0x3c, 0x13, 0x17, 0x3c, 0x4b, 0x13, 0x4e, 0x18, 0xe1,
+	0xef, 0x05, 0x2a, 0x62, 0xfb, 0xd0, 0x4a, 0x13, 0x15, 0x7a, 0xc3, 0xd6, 0xd8, 0x8f, 0xf4, 0x27,
+	0xfb, 0x10, 0x76, 0x32, 0xbe, 0x46, 0xa9, 0xc2, 0x6d, 0x03, 0xba, 0x6a, 0x34, 0x81, 0x83, 0x7a,
+	0xfb, 0x2c, 0xcb, 0x4a, 0x85, 0x9a, 0xef, 0x5d, 0xe3, 0xbf, 0x69, 0x03, 0xcc, 0xd7, 0x62, 0x31,
+	0x27, 0x4e, 0x85, 0x62, 0x0f, 0x21, 0x48, 0x45, 0xbc, 0x92, 0xf9, 0x6b, 0x89, 0x4a, 0x73, 0xbd,
+	0x71, 0x27, 0x82, 0x54, 0xfc, 0xe4, 0x10, 0xf6, 0x05, 0xdc, 0xcf, 0xb8, 0xa2, 0x58, 0x11, 0x97,
+	0x84, 0x49, 0xcc, 0x29, 0xdc, 0x1e, 0x7a, 0xe3, 0x56, 0xd4, 0xd3, 0xf0, 0xdc, 0xa2, 0x33, 0x62,
+	0x63, 0xd8, 0x37, 0xbc, 0xb3, 0x54, 0xa4, 0xea, 0xdc, 0x12, 0x5b, 0x86, 0xb8, 0xa7, 0xf1, 0x53,
+	0x07, 0xcf, 0xa8, 0x56, 0x2c, 0x16, 0x0b, 0x54, 0x4a, 0x13, 0xdb, 0x0d, 0x45, 0x8b, 0xce, 0x88,
+	0x1d, 0x82, 0x01, 0xe2, 0xa4, 0x90, 0x9c, 0xd2, 0x5c, 0x84, 0xf7, 0x0c, 0xab, 0xab, 0xc1, 0x27,
+	0x0e, 0x63, 0x9f, 0x02, 0x18, 0x12, 0x4a, 0x99, 0xcb, 0x70, 0x67, 0xe8, 0x8d, 0xfd, 0xc8, 0xd7,
+	0xc8, 0x89, 0x06, 0xd8, 0x67, 0xd0, 0x55, 0x6b, 0xb1, 0xc0, 0x24, 0xd6, 0x8e, 0xab, 0x70, 0xd7,
+	0x48, 0x04, 0x16, 0xfb, 0x59, 0x43, 0xec, 0x13, 0xf0, 0xff, 0xe0, 0x84, 0x72, 0xc9, 0xe5, 0x6f,
+	0x61, 0xc7, 0xac, 0xd7, 0x00, 0x7b, 0x04, 0x0f, 0xec, 0x21, 0x30, 0x23, 0xde, 0x3c, 0xb2, 0x6f,
+	0x98, 0xcc, 0x1c, 0x46, 0xaf, 0xd5, 0xe7, 0x2e, 0x9d, 0xb0, 0x5b, 0xec, 0xc1, 0xc0, 0x1c, 0x6c,
+	0xaf, 0x62, 0xdb, 0xd3, 0x3d, 0x84, 0x40, 0xa2, 0xa2, 0x5c, 0x5a, 0xbb, 0x02, 0x23, 0x09, 0x25,
+	0xd4, 0xb0, 0xea, 0x8c, 0xa7, 0x59, 0x21, 0x51, 0x93, 0xba, 0xb5, 0x55, 0xa7, 0x16, 0x9d, 0x11,
+	0xeb, 0x43, 0xc7, 0x51, 0x54, 0xd8, 0x33, 0x84, 0xaa, 0x66, 0x43, 0xe8, 0x0a, 0xfc, 0x93, 0x62,
+	0x7d, 0x67, 0x2d, 0xb0, 0x67, 0xff, 0x8b, 0xc6, 0x74, 0x1f, 0xcc, 0x68, 0xf4, 0xd6, 0x03, 0xff,
+	0x85, 0xee, 0x8e, 0x79, 0xfa, 0x17, 0x32, 0x06, 0x6d, 0xc1, 0x97, 0x68, 0x5a, 0xc1, 0x8f, 0xcc,
+	0x37, 0x0b, 0x61, 0x17, 0x05, 0xc9, 0x14, 0x95, 0x0b, 0xbf, 0x2c, 0xd9, 0x01, 0xdc, 0x7b, 0xb5,
+	0x26, 0x54, 0x2e, 0x6b, 0x5b, 0xb0, 0xaf, 0x60, 0x5f, 0xe0, 0x6b, 0x4e, 0xe9, 0x05, 0xc6, 0xe5,
+	0x46, 0x9b, 0xf1, 0xfd, 0x12, 0x3f, 0x71, 0x02, 0x87, 0xd0, 0xab, 0xa8, 0xe7, 0x3a, 0x22, 0x9d,
+	0x72, 0x3b, 0xea, 0x96, 0xe0, 0x0f, 0x3a, 0xa3, 0x2f, 0xa1, 0xda, 0x17, 0x2f, 0x53, 0xa5, 0x50,
+	0x99, 0xa8, 0xdb, 0xd1, 0x5e, 0x09, 0xbf, 0x34, 0xe8, 0xe8, 0x19, 0x3c, 0x78, 0x8a, 0x54, 0x5d,
+	0x46, 0x45, 0xa8, 0x56, 0xb9, 0x50, 0xc8, 0x8e, 0xae, 0x8d, 0x43, 0x70, 0x1c, 0x4e, 0xae, 0x4d,
+	0xe4, 0xa4, 0xda, 0x52, 0x0d, 0xca, 0xdf, 0xdb, 0x10, 0xbc, 0x44, 0x3a, 0xcf, 0x13, 0x3d, 0x2a,
+	0x66, 0x00, 0x97, 0xa6, 0x74, 0xce, 0xb8, 0x4a, 0x3b, 0xb0, 0xe0, 0x59, 0x66, 0x9d, 0x69, 0x47,
+	0xb6, 0xd0, 0x6c, 0x93, 0xbc, 0x35, 0xa6, 0x1d, 0xb9, 0x8a, 0x7d, 0x0c, 0xbe, 0xc8, 0x29, 0x3e,
+	0xcb, 0x0b, 0x91, 0x18, 0x4b, 0xda, 0x51, 0x47, 0xe4, 0x74, 0xaa, 0x6b, 0xdd, 0x8a, 0xd2, 0x8e,
+	0x2f, 0x26, 0xce, 0x87, 0x1a, 0xd0, 0x21, 0x4b, 0xa4, 0x42, 0x0a, 0x4c, 0xdc, 0xed, 0xab, 0x5a,
+	0xbb, 0x48, 0x39, 0xf1, 0x2c, 0xce, 0x38, 0xa1, 0x58, 0xac, 0x5d, 0xa3, 0x77, 0x0d, 0xf8, 0xc2,
+	0x62, 0xba, 0xdd, 0x56, 0x8f, 0x8f, 0x2a, 0x8a, 0xed, 0x75, 0x58, 0x3d, 0x3e, 0x6a, 0x12, 0xa6,
+	0xd3, 0x8a, 0xe0, 0x3b, 0xc2, 0x74, 0xea, 0x08, 0xa3, 0x5f, 0x00, 0xac, 0x51, 0xc6, 0x91, 0x9b,
+	0x3a, 0xe5, 0x1b, 0xd8, 0xb5, 0xbe, 0xd8, 0x77, 0x2a, 0x38, 0xee, 0x6f, 0x18, 0xdd, 0xb0, 0x34,
+	0x2a, 0xa9, 0xa3, 0xe7, 0x8d, 0xd8, 0xcc, 0x4a, 0x19, 0xdb, 0xa3, 0x8d, 0xd8, 0x3e, 0xba, 0x31,
+	0x36, 0xb3, 0xc5, 0x11, 0x8f, 0xff, 0x69, 0x41, 0x77, 0xa6, 0x9f, 0xde, 0x39, 0xca, 0x8b, 0x74,
+	0x81, 0xec, 0x09, 0x40, 0xfd, 0x42, 0xb2, 0xe1, 0x86, 0xc2, 0x7b, 0x6f, 0x6f, 0xff, 0x60, 0x83,
+	0x71, 0xb2, 0x5c, 0xd1, 0x9a, 0x3d, 0x87, 0xde, 0xb5, 0x77, 0x96, 0x1d, 0xde, 0x2a, 0x54, 0xbf,
+	0xc2, 0xb7, 0x68, 0x4d, 0x61, 0x27, 0x42, 0x3d, 0x8f, 0xec, 0xc6, 0xf5, 0xfe, 0xe6, 0x2d, 0x1b,
+	0xef, 0xf5, 0x77, 0xd0, 0x7b, 0x8a, 0xd4, 0x00, 0xee, 0xac, 0xf0, 0xa3, 0x51, 0xa8, 0x47, 0xe4,
+	0x16, 0x85, 0xcf, 0x37, 0xd0, 0x9b, 0xc7, 0xaa, 0x29, 0x66, 0x7a, 0xe2, 0x8e, 0x62, 0xcd, 0xb0,
+	0xbf, 0x1f, 0xbd, 0xbd, 0x1c, 0x78, 0xef, 0x2e, 0x07, 0xde, 0x7f, 0x97, 0x03, 0xef, 0xcd, 0xd5,
+	0x60, 0xeb, 0xdd, 0xd5, 0x60, 0xeb, 0xdf, 0xab, 0xc1, 0xd6, 0xaf, 0x1d, 0x65, 0xb3, 0x54, 0xaf,
+	0x76, 0xcc, 0x8f, 0xe6, 0xd7, 0xff, 0x0f, 0x00, 0x60, 0x9d, 0x38, 0xb1, 0x5e, 0x07, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	Invalidate(ctx context.Context, in *InvalidateRequest, opts ...grpc.CallOption) (*Empty, error)
+	InvalidateAll(ctx context.Context, in *InvalidateAllRequest, opts ...grpc.CallOption) (*Empty, error)
+	Resync(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SyncStatus, error)
+	GetSyncStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SyncStatus, error)
+	GetLayerSizes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetLayerSizesResponse, error)
+	GetLayerStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetLayerStatsResponse, error)
+}
+
+type adminServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminServiceClient(cc *grpc.ClientConn) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) Invalidate(ctx context.Context, in *InvalidateRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/test.art.unit.AdminService/Invalidate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) InvalidateAll(ctx context.Context, in *InvalidateAllRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/test.art.unit.AdminService/InvalidateAll", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Resync(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SyncStatus, error) {
+	out := new(SyncStatus)
+	err := c.cc.Invoke(ctx, "/test.art.unit.AdminService/Resync", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetSyncStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SyncStatus, error) {
+	out := new(SyncStatus)
+	err := c.cc.Invoke(ctx, "/test.art.unit.AdminService/GetSyncStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetLayerSizes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetLayerSizesResponse, error) {
+	out := new(GetLayerSizesResponse)
+	err := c.cc.Invoke(ctx, "/test.art.unit.AdminService/GetLayerSizes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetLayerStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetLayerStatsResponse, error) {
+	out := new(GetLayerStatsResponse)
+	err := c.cc.Invoke(ctx, "/test.art.unit.AdminService/GetLayerStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+type AdminServiceServer interface {
+	Invalidate(context.Context, *InvalidateRequest) (*Empty, error)
+	InvalidateAll(context.Context, *InvalidateAllRequest) (*Empty, error)
+	Resync(context.Context, *Empty) (*SyncStatus, error)
+	GetSyncStatus(context.Context, *Empty) (*SyncStatus, error)
+	GetLayerSizes(context.Context, *Empty) (*GetLayerSizesResponse, error)
+	GetLayerStats(context.Context, *Empty) (*GetLayerStatsResponse, error)
+}
+
+// UnimplementedAdminServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedAdminServiceServer struct {
+}
+
+func (*UnimplementedAdminServiceServer) Invalidate(ctx context.Context, req *InvalidateRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Invalidate not implemented")
+}
+func (*UnimplementedAdminServiceServer) InvalidateAll(ctx context.Context, req *InvalidateAllRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InvalidateAll not implemented")
+}
+func (*UnimplementedAdminServiceServer) Resync(ctx context.Context, req *Empty) (*SyncStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resync not implemented")
+}
+func (*UnimplementedAdminServiceServer) GetSyncStatus(ctx context.Context, req *Empty) (*SyncStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSyncStatus not implemented")
+}
+func (*UnimplementedAdminServiceServer) GetLayerSizes(ctx context.Context, req *Empty) (*GetLayerSizesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLayerSizes not implemented")
+}
+func (*UnimplementedAdminServiceServer) GetLayerStats(ctx context.Context, req *Empty) (*GetLayerStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLayerStats not implemented")
+}
+
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
+}
+
+func _AdminService_Invalidate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvalidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Invalidate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.art.unit.AdminService/Invalidate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Invalidate(ctx, req.(*InvalidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_InvalidateAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvalidateAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).InvalidateAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.art.unit.AdminService/InvalidateAll",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).InvalidateAll(ctx, req.(*InvalidateAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Resync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Resync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.art.unit.AdminService/Resync",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Resync(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetSyncStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetSyncStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.art.unit.AdminService/GetSyncStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetSyncStatus(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetLayerSizes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetLayerSizes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.art.unit.AdminService/GetLayerSizes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetLayerSizes(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetLayerStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetLayerStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.art.unit.AdminService/GetLayerStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetLayerStats(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "test.art.unit.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Invalidate",
+			Handler:    _AdminService_Invalidate_Handler,
+		},
+		{
+			MethodName: "InvalidateAll",
+			Handler:    _AdminService_InvalidateAll_Handler,
+		},
+		{
+			MethodName: "Resync",
+			Handler:    _AdminService_Resync_Handler,
+		},
+		{
+			MethodName: "GetSyncStatus",
+			Handler:    _AdminService_GetSyncStatus_Handler,
+		},
+		{
+			MethodName: "GetLayerSizes",
+			Handler:    _AdminService_GetLayerSizes_Handler,
+		},
+		{
+			MethodName: "GetLayerStats",
+			Handler:    _AdminService_GetLayerStats_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
+
+func (m *InvalidateRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *InvalidateRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *InvalidateRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for iNdEx := len(m.Layers) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Layers[iNdEx])
+			copy(dAtA[i:], m.Layers[iNdEx])
+			i = encodeVarintAdmin(dAtA, i, uint64(len(m.Layers[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Ids) > 0 {
+		for iNdEx := len(m.Ids) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Ids[iNdEx])
+			copy(dAtA[i:], m.Ids[iNdEx])
+			i = encodeVarintAdmin(dAtA, i, uint64(len(m.Ids[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *InvalidateAllRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *InvalidateAllRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *InvalidateAllRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for iNdEx := len(m.Layers) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Layers[iNdEx])
+			copy(dAtA[i:], m.Layers[iNdEx])
+			i = encodeVarintAdmin(dAtA, i, uint64(len(m.Layers[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SyncStatus) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SyncStatus) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SyncStatus) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.NextSyncAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.NextSyncAt))
+		i--
+		dAtA[i] = 0x70
+	}
+	if m.Failures != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Failures))
+		i--
+		dAtA[i] = 0x68
+	}
+	if m.LastFailureAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LastFailureAt))
+		i--
+		dAtA[i] = 0x60
+	}
+	if m.RestoredAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.RestoredAt))
+		i--
+		dAtA[i] = 0x58
+	}
+	if len(m.LastDeltaError) > 0 {
+		i -= len(m.LastDeltaError)
+		copy(dAtA[i:], m.LastDeltaError)
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.LastDeltaError)))
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.LastDeltaSuccessAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LastDeltaSuccessAt))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.Watermark != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Watermark))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.SyncedUnits != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SyncedUnits))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.LastError) > 0 {
+		i -= len(m.LastError)
+		copy(dAtA[i:], m.LastError)
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.LastError)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.LastDuration != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LastDuration))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.LastSuccessAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LastSuccessAt))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.LastFinishedAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LastFinishedAt))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.LastStartedAt != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LastStartedAt))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.InProgress {
+		i--
+		if m.InProgress {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *LayerSize) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *LayerSize) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *LayerSize) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.NegativeMisses != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.NegativeMisses))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.NegativeHits != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.NegativeHits))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.NegativeEntries != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.NegativeEntries))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Bytes != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Bytes))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Entries != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Entries))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GetLayerSizesResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GetLayerSizesResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GetLayerSizesResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for iNdEx := len(m.Layers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Layers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintAdmin(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MethodStats) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MethodStats) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MethodStats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.P99Latency != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.P99Latency))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.P50Latency != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.P50Latency))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.TotalLatency != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.TotalLatency))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.Returned != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Returned))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Requested != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Requested))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.NotFound != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.NotFound))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Errors != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Errors))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Calls != 0 {
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Calls))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Method) > 0 {
+		i -= len(m.Method)
+		copy(dAtA[i:], m.Method)
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Method)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *LayerStats) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *LayerStats) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *LayerStats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Methods) > 0 {
+		for iNdEx := len(m.Methods) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Methods[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintAdmin(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GetLayerStatsResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GetLayerStatsResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GetLayerStatsResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for iNdEx := len(m.Layers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Layers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintAdmin(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintAdmin(dAtA []byte, offset int, v uint64) int {
+	offset -= sovAdmin(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *InvalidateRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Ids) > 0 {
+		for _, s := range m.Ids {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	if len(m.Layers) > 0 {
+		for _, s := range m.Layers {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *InvalidateAllRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for _, s := range m.Layers {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *SyncStatus) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.InProgress {
+		n += 2
+	}
+	if m.LastStartedAt != 0 {
+		n += 1 + sovAdmin(uint64(m.LastStartedAt))
+	}
+	if m.LastFinishedAt != 0 {
+		n += 1 + sovAdmin(uint64(m.LastFinishedAt))
+	}
+	if m.LastSuccessAt != 0 {
+		n += 1 + sovAdmin(uint64(m.LastSuccessAt))
+	}
+	if m.LastDuration != 0 {
+		n += 1 + sovAdmin(uint64(m.LastDuration))
+	}
+	l = len(m.LastError)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.SyncedUnits != 0 {
+		n += 1 + sovAdmin(uint64(m.SyncedUnits))
+	}
+	if m.Watermark != 0 {
+		n += 1 + sovAdmin(uint64(m.Watermark))
+	}
+	if m.LastDeltaSuccessAt != 0 {
+		n += 1 + sovAdmin(uint64(m.LastDeltaSuccessAt))
+	}
+	l = len(m.LastDeltaError)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.RestoredAt != 0 {
+		n += 1 + sovAdmin(uint64(m.RestoredAt))
+	}
+	if m.LastFailureAt != 0 {
+		n += 1 + sovAdmin(uint64(m.LastFailureAt))
+	}
+	if m.Failures != 0 {
+		n += 1 + sovAdmin(uint64(m.Failures))
+	}
+	if m.NextSyncAt != 0 {
+		n += 1 + sovAdmin(uint64(m.NextSyncAt))
+	}
+	return n
+}
+
+func (m *LayerSize) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.Entries != 0 {
+		n += 1 + sovAdmin(uint64(m.Entries))
+	}
+	if m.Bytes != 0 {
+		n += 1 + sovAdmin(uint64(m.Bytes))
+	}
+	if m.NegativeEntries != 0 {
+		n += 1 + sovAdmin(uint64(m.NegativeEntries))
+	}
+	if m.NegativeHits != 0 {
+		n += 1 + sovAdmin(uint64(m.NegativeHits))
+	}
+	if m.NegativeMisses != 0 {
+		n += 1 + sovAdmin(uint64(m.NegativeMisses))
+	}
+	return n
+}
+
+func (m *GetLayerSizesResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for _, e := range m.Layers {
+			l = e.Size()
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *MethodStats) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Method)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.Calls != 0 {
+		n += 1 + sovAdmin(uint64(m.Calls))
+	}
+	if m.Errors != 0 {
+		n += 1 + sovAdmin(uint64(m.Errors))
+	}
+	if m.NotFound != 0 {
+		n += 1 + sovAdmin(uint64(m.NotFound))
+	}
+	if m.Requested != 0 {
+		n += 1 + sovAdmin(uint64(m.Requested))
+	}
+	if m.Returned != 0 {
+		n += 1 + sovAdmin(uint64(m.Returned))
+	}
+	if m.TotalLatency != 0 {
+		n += 1 + sovAdmin(uint64(m.TotalLatency))
+	}
+	if m.P50Latency != 0 {
+		n += 1 + sovAdmin(uint64(m.P50Latency))
+	}
+	if m.P99Latency != 0 {
+		n += 1 + sovAdmin(uint64(m.P99Latency))
+	}
+	return n
+}
+
+func (m *LayerStats) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if len(m.Methods) > 0 {
+		for _, e := range m.Methods {
+			l = e.Size()
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *GetLayerStatsResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Layers) > 0 {
+		for _, e := range m.Layers {
+			l = e.Size()
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func sovAdmin(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozAdmin(x uint64) (n int) {
+	return sovAdmin(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *InvalidateRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InvalidateRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InvalidateRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ids", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ids = append(m.Ids, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Layers", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Layers = append(m.Layers, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InvalidateAllRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InvalidateAllRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InvalidateAllRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Layers", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Layers = append(m.Layers, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SyncStatus) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SyncStatus: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SyncStatus: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InProgress", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.InProgress = bool(v != 0)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastStartedAt", wireType)
+			}
+			m.LastStartedAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastStartedAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastFinishedAt", wireType)
+			}
+			m.LastFinishedAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastFinishedAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastSuccessAt", wireType)
+			}
+			m.LastSuccessAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastSuccessAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastDuration", wireType)
+			}
+			m.LastDuration = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastDuration |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastError", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LastError = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SyncedUnits", wireType)
+			}
+			m.SyncedUnits = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SyncedUnits |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Watermark", wireType)
+			}
+			m.Watermark = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Watermark |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastDeltaSuccessAt", wireType)
+			}
+			m.LastDeltaSuccessAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastDeltaSuccessAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastDeltaError", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LastDeltaError = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RestoredAt", wireType)
+			}
+			m.RestoredAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RestoredAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastFailureAt", wireType)
+			}
+			m.LastFailureAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastFailureAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Failures", wireType)
+			}
+			m.Failures = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Failures |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NextSyncAt", wireType)
+			}
+			m.NextSyncAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NextSyncAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *LayerSize) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: LayerSize: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: LayerSize: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
+			}
+			m.Entries = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Entries |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bytes", wireType)
+			}
+			m.Bytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Bytes |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NegativeEntries", wireType)
+			}
+			m.NegativeEntries = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NegativeEntries |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NegativeHits", wireType)
+			}
+			m.NegativeHits = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NegativeHits |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NegativeMisses", wireType)
+			}
+			m.NegativeMisses = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NegativeMisses |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GetLayerSizesResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GetLayerSizesResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GetLayerSizesResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Layers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Layers = append(m.Layers, &LayerSize{})
+			if err := m.Layers[len(m.Layers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MethodStats) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MethodStats: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MethodStats: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Method = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Calls", wireType)
+			}
+			m.Calls = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Calls |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Errors", wireType)
+			}
+			m.Errors = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Errors |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NotFound", wireType)
+			}
+			m.NotFound = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NotFound |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Requested", wireType)
+			}
+			m.Requested = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Requested |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Returned", wireType)
+			}
+			m.Returned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Returned |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalLatency", wireType)
+			}
+			m.TotalLatency = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalLatency |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field P50Latency", wireType)
+			}
+			m.P50Latency = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.P50Latency |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field P99Latency", wireType)
+			}
+			m.P99Latency = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.P99Latency |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *LayerStats) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: LayerStats: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: LayerStats: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Methods", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Methods = append(m.Methods, &MethodStats{})
+			if err := m.Methods[len(m.Methods)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GetLayerStatsResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GetLayerStatsResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GetLayerStatsResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Layers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Layers = append(m.Layers, &LayerStats{})
+			if err := m.Layers[len(m.Layers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipAdmin(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthAdmin
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupAdmin
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthAdmin
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthAdmin        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowAdmin          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupAdmin = fmt.Errorf("proto: unexpected end of group")
+)
@@ -2,23 +2,128 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/AltMax/art-test/models"
 	"github.com/AltMax/art-test/units"
-	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/rs/zerolog"
 )
 
+const revalidateTimeout = 10 * time.Second
+
 type Cache struct {
 	units.Units
-	cache *lru.Cache[string, *models.Unit]
+	cache *instrumented
+
+	policy        Policy
+	ttl           time.Duration
+	staleTTL      time.Duration
+	revalidating  sync.Map
+	revalidations uint64
+
+	maxBytes      int64
+	maxEntryBytes int64
+	heapLimit     uint64
+
+	negative *units.NegativeCache
+
+	// writeMu makes checking the cached version and adding a unit atomic,
+	// reads don't take it
+	writeMu sync.Mutex
+	// removed keeps units removed while reads of the next layer are in
+	// flight, so reads don't cache units deleted after they were read
+	removed *units.Tombstones
+
+	maxRemovedRatio      float64
+	removalConfirmations int
+	removals             *units.RemovalGuard
+	reconciliationMu     sync.Mutex
+	lastReconciliation   units.Reconciliation
+}
+
+type Option func(*Cache)
+
+// WithMaxRemovedRatio skips removal of units missing from a full fetch when
+// the fetch has shrunk by more than ratio compared with the previous one,
+// unless confirmations consecutive fetches agree on the smaller total.
+func WithMaxRemovedRatio(ratio float64, confirmations int) Option {
+	return func(c *Cache) {
+		c.maxRemovedRatio = ratio
+		c.removalConfirmations = confirmations
+	}
+}
+
+// WithNegativeCache makes the cache remember ids that were not found,
+// so they are not looked up in the next layer again until they expire.
+func WithNegativeCache(negative *units.NegativeCache) Option {
+	return func(c *Cache) {
+		c.negative = negative
+	}
+}
+
+// WithMaxBytes bounds the cache by total bytes of ids and data of units
+// besides the number of units. Units larger than maxEntryBytes are not cached,
+// so a single huge unit doesn't flush everything, 0 means a tenth of maxBytes.
+func WithMaxBytes(maxBytes, maxEntryBytes int64) Option {
+	return func(c *Cache) {
+		c.maxBytes = maxBytes
+		c.maxEntryBytes = maxEntryBytes
+		if c.maxEntryBytes == 0 {
+			c.maxEntryBytes = maxBytes / 10
+		}
+	}
+}
+
+// WithHeapLimit makes AdaptToMemoryPressure shrink the byte budget
+// when the go heap approaches heapLimit bytes.
+func WithHeapLimit(heapLimit uint64) Option {
+	return func(c *Cache) {
+		c.heapLimit = heapLimit
+	}
+}
+
+// WithPolicy selects the eviction policy, LRU is used by default.
+// Only LRU and TTL support WithMaxBytes.
+func WithPolicy(policy Policy) Option {
+	return func(c *Cache) {
+		c.policy = policy
+	}
+}
+
+// WithTTL sets the time units are fresh for when the TTL policy is used.
+// Units older than ttl are still returned for staleTTL more while they are
+// refreshed from the next layer in background.
+func WithTTL(ttl, staleTTL time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+		c.staleTTL = staleTTL
+	}
 }
 
-func NewCache(store units.Units, size int) (*Cache, error) {
-	l, err := lru.New[string, *models.Unit](size)
+// NewCache returns a cache of at most size units.
+func NewCache(store units.Units, size int, opts ...Option) (*Cache, error) {
+	c := &Cache{
+		Units:                store,
+		policy:               LRU,
+		maxRemovedRatio:      units.DefaultMaxRemovedRatio,
+		removalConfirmations: units.DefaultRemovalConfirmations,
+		removed:              units.NewTombstones(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.removals = units.NewRemovalGuard(c.maxRemovedRatio, c.removalConfirmations)
+
+	p, err := c.newPolicy(size)
 	if err != nil {
 		return nil, err
 	}
-	return &Cache{Units: store, cache: l}, nil
+	c.cache = p
+
+	return c, nil
 }
 
 func (c *Cache) Create(ctx context.Context, unit *models.Unit) error {
@@ -27,6 +132,7 @@ func (c *Cache) Create(ctx context.Context, unit *models.Unit) error {
 		return err
 	}
 
+	c.negative.Remove(unit.ID)
 	c.add(unit)
 
 	return nil
@@ -38,6 +144,7 @@ func (c *Cache) Update(ctx context.Context, id string, data []byte) (*models.Uni
 		return nil, err
 	}
 
+	c.negative.Remove(id)
 	c.add(updatedUnit)
 
 	return updatedUnit, nil
@@ -50,68 +157,314 @@ func (c *Cache) Delete(ctx context.Context, id string) error {
 	}
 
 	c.remove(id)
+	c.negative.Add(id)
 
 	return nil
 }
 
 func (c *Cache) FindByID(ctx context.Context, id string) (*models.Unit, error) {
-	unit := c.getByID(id)
+	unit := c.getByID(ctx, id)
 	if unit != nil {
 		return unit, nil
 	}
+	if c.negative.Contains(id) {
+		return nil, units.ErrNotFound
+	}
 
+	start := c.removed.Begin()
+	defer c.removed.End(start)
+	gen := c.negative.Generation()
 	unit, err := c.Units.FindByID(ctx, id)
+	if errors.Is(err, units.ErrNotFound) {
+		c.negative.AddSince(gen, id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	c.add(unit)
+	c.addRead(start, unit)
 
 	return unit, nil
 }
 
 func (c *Cache) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
-	return units.FindByIDs(ctx, ids, c.getByIDs, c.add, c.Units)
+	start := c.removed.Begin()
+	defer c.removed.End(start)
+	getByIDs := func(ids []string) []*models.Unit {
+		return c.getByIDs(ctx, ids)
+	}
+	addRead := func(found ...*models.Unit) {
+		c.addRead(start, found...)
+	}
+	return units.FindByIDs(ctx, ids, getByIDs, addRead, c.Units, c.negative)
 }
 
+// add caches units unless newer versions of them are cached already,
+// so a unit read before a concurrent write doesn't overwrite the written one.
+// Units without UpdatedAt are acknowledged before the database assigned them
+// a version (see writebehind), they are always cached and reads in flight
+// don't replace them.
 func (c *Cache) add(units ...*models.Unit) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	for _, unit := range units {
+		if unit.UpdatedAt.IsZero() {
+			c.removed.Add(unit.ID)
+		} else if cached, ok := c.cache.Peek(unit.ID); ok && cached.NewerThan(unit) {
+			continue
+		}
+		c.cache.Add(unit.ID, unit)
+	}
+}
+
+// addRead is add for units read by a read of the next layer that began
+// at start, units removed since are not cached.
+func (c *Cache) addRead(start uint64, units ...*models.Unit) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	for _, unit := range units {
+		if c.removed.Removed(unit.ID, start) {
+			continue
+		}
+		if cached, ok := c.cache.Peek(unit.ID); ok && cached.NewerThan(unit) {
+			continue
+		}
 		c.cache.Add(unit.ID, unit)
 	}
 }
 
-func (c *Cache) remove(id string) {
-	c.cache.Remove(id)
+func (c *Cache) remove(ids ...string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.removed.Add(ids...)
+	for _, id := range ids {
+		c.cache.Remove(id)
+	}
 }
 
-func (c *Cache) getByID(id string) *models.Unit {
-	unit, ok := c.cache.Get(id)
+func (c *Cache) Invalidate(ids ...string) {
+	c.remove(ids...)
+	c.negative.Remove(ids...)
+}
+
+// InvalidateAll removes all units, reads in flight don't cache them again.
+func (c *Cache) InvalidateAll() {
+	c.writeMu.Lock()
+	c.removed.AddAll()
+	c.cache.Purge()
+	c.writeMu.Unlock()
+	c.negative.Purge()
+}
+
+func (c *Cache) NegativeStats() units.NegativeStats {
+	return c.negative.Stats()
+}
+
+func (c *Cache) Len() int {
+	return c.cache.Len()
+}
+
+// Bytes is the size of ids and data of cached units,
+// it's 0 for policies that don't support WithMaxBytes.
+func (c *Cache) Bytes() int64 {
+	return c.cache.Bytes()
+}
+
+// Stats describes usage of the cache.
+type Stats struct {
+	Policy        Policy
+	Entries       int
+	Bytes         int64
+	Hits          uint64
+	Misses        uint64
+	Revalidations uint64
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Policy:        c.policy,
+		Entries:       c.cache.Len(),
+		Bytes:         c.cache.Bytes(),
+		Hits:          atomic.LoadUint64(&c.cache.hits),
+		Misses:        atomic.LoadUint64(&c.cache.misses),
+		Revalidations: atomic.LoadUint64(&c.revalidations),
+	}
+}
+
+// revalidate refreshes a stale unit from the next layer in background with
+// the logger of ctx, the stale unit is kept if the next layer fails.
+func (c *Cache) revalidate(ctx context.Context, stale *models.Unit) {
+	id := stale.ID
+	if _, inProgress := c.revalidating.LoadOrStore(id, struct{}{}); inProgress {
+		return
+	}
+	go func() {
+		defer c.revalidating.Delete(id)
+
+		ctx, cancel := context.WithTimeout(zerolog.Ctx(ctx).WithContext(context.Background()), revalidateTimeout)
+		defer cancel()
+
+		unit, err := c.Units.FindByID(ctx, id)
+		switch {
+		case err == nil:
+			c.replaceStale(stale, unit)
+		case errors.Is(err, units.ErrNotFound):
+			c.replaceStale(stale, nil)
+		default:
+			zerolog.Ctx(ctx).Warn().Err(err).Str("id", id).Msg("cache revalidation failed")
+		}
+		atomic.AddUint64(&c.revalidations, 1)
+	}()
+}
+
+// replaceStale replaces a stale unit with its version read from the next
+// layer, unit is nil when it was not found. Nothing is replaced when the unit
+// was written, removed or invalidated during revalidation, so a unit deleted
+// meanwhile is not cached again.
+func (c *Cache) replaceStale(stale, unit *models.Unit) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if cached, ok := c.cache.Peek(stale.ID); !ok || cached != stale {
+		return
+	}
+	if unit == nil {
+		c.cache.Remove(stale.ID)
+		return
+	}
+	if !stale.NewerThan(unit) {
+		c.cache.Add(unit.ID, unit)
+	}
+}
+
+func (c *Cache) getByID(ctx context.Context, id string) *models.Unit {
+	unit, stale, ok := c.cache.GetStale(id)
 	if !ok {
 		return nil
 	}
+	if stale {
+		c.revalidate(ctx, unit)
+	}
 
 	return unit
 }
 
-func (c *Cache) getByIDs(ids []string) []*models.Unit {
+func (c *Cache) getByIDs(ctx context.Context, ids []string) []*models.Unit {
 	units := make([]*models.Unit, 0, len(ids))
 	for _, id := range ids {
-		if unit, ok := c.cache.Get(id); ok {
+		if unit := c.getByID(ctx, id); unit != nil {
 			units = append(units, unit)
 		}
 	}
 	return units
 }
 
+// FetchAll refreshes cached units and removes the ones that are missing
+// from the result, units that are not cached are not added.
 func (c *Cache) FetchAll(ctx context.Context) (models.Units, error) {
-	units, err := c.Units.FetchAll(ctx)
+	var fetched models.Units
+	err := c.Scan(ctx, func(batch models.Units) error {
+		fetched = append(fetched, batch...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	for _, unit := range units {
-		if c.cache.Contains(unit.ID) {
+	return fetched, nil
+}
+
+// Scan is FetchAll that refreshes cached units batch by batch
+// and passes the batches to fn.
+func (c *Cache) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	before := c.cache.Keys()
+	cached := make(map[string]struct{}, len(before))
+	for _, id := range before {
+		cached[id] = struct{}{}
+	}
+	// only ids that were cached are needed to find vanished ones
+	fetched := make(map[string]struct{}, len(before))
+	total := 0
+	var r units.Reconciliation
+	err := units.Scan(ctx, c.Units, func(batch models.Units) error {
+		total += len(batch)
+		for _, unit := range batch {
+			if _, ok := cached[unit.ID]; ok {
+				fetched[unit.ID] = struct{}{}
+			}
+		}
+		c.refresh(batch, &r)
+		return fn(batch)
+	})
+	if err != nil {
+		return err
+	}
+	c.negative.Purge()
+	c.removeVanished(before, fetched, total, &r)
+	units.LogReconciliation(ctx, "cache", r)
+	return nil
+}
+
+// refresh updates cached units of a batch of a full fetch.
+func (c *Cache) refresh(batch models.Units, r *units.Reconciliation) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	for _, unit := range batch {
+		cached, ok := c.cache.Peek(unit.ID)
+		if !ok || cached.NewerThan(unit) {
+			continue
+		}
+		c.cache.Add(unit.ID, unit)
+		if !cached.UpdatedAt.Equal(unit.UpdatedAt) || len(cached.Data) != len(unit.Data) {
+			r.Updated++
+		}
+	}
+}
+
+// removeVanished removes units that were cached before a full fetch
+// but are missing from it.
+func (c *Cache) removeVanished(before []string, fetched map[string]struct{}, total int, r *units.Reconciliation) {
+	var vanished []string
+	if c.removals.Accept(total) {
+		vanished = units.Vanished(before, fetched)
+	} else {
+		r.Skipped = true
+	}
+	c.removed.Add(vanished...)
+	for _, id := range vanished {
+		if c.cache.Remove(id) {
+			r.Removed++
+		}
+	}
+
+	c.reconciliationMu.Lock()
+	c.lastReconciliation = *r
+	c.reconciliationMu.Unlock()
+}
+
+// LastReconciliation is the result of the last FetchAll.
+func (c *Cache) LastReconciliation() units.Reconciliation {
+	c.reconciliationMu.Lock()
+	defer c.reconciliationMu.Unlock()
+	return c.lastReconciliation
+}
+
+// FetchChanges refreshes cached units that were updated and removes deleted
+// ones, units that are not cached are not added.
+func (c *Cache) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	changes, err := c.Units.FetchChanges(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	c.writeMu.Lock()
+	for _, unit := range changes.Updated {
+		c.negative.Remove(unit.ID)
+		if cached, ok := c.cache.Peek(unit.ID); ok && !cached.NewerThan(unit) {
 			c.cache.Add(unit.ID, unit)
 		}
 	}
-	return units, nil
+	c.removed.Add(changes.Deleted...)
+	for _, id := range changes.Deleted {
+		c.cache.Remove(id)
+	}
+	c.writeMu.Unlock()
+	return changes, nil
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
 	"github.com/AltMax/art-test/units/mocks"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -26,7 +27,7 @@ func Test_Create(t *testing.T) {
 	err = testCache.Create(ctx, unit)
 	require.NoError(t, err)
 
-	chachedUnit := testCache.getByID(unit.ID)
+	chachedUnit := testCache.getByID(context.Background(), unit.ID)
 	require.Equal(t, unit, chachedUnit)
 }
 
@@ -40,7 +41,7 @@ func Test_Update(t *testing.T) {
 	unit := randomUnit()
 
 	testCache.add(unit)
-	chachedUnit := testCache.getByID(unit.ID)
+	chachedUnit := testCache.getByID(context.Background(), unit.ID)
 	require.Equal(t, unit, chachedUnit)
 
 	unit.Data = []byte("updated data")
@@ -49,10 +50,32 @@ func Test_Update(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, unit, updatedUnit)
 
-	chachedUnit = testCache.getByID(unit.ID)
+	chachedUnit = testCache.getByID(context.Background(), unit.ID)
 	require.Equal(t, unit, chachedUnit)
 }
 
+func Test_Update_Unversioned(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10)
+	require.NoError(t, err)
+
+	unit := randomUnit()
+	unit.UpdatedAt = time.Now().UTC()
+	testCache.add(unit)
+	start := testCache.removed.Begin()
+
+	// a write acknowledged before the database assigned it a version
+	// replaces the cached unit and a read in flight doesn't bring it back
+	written := &models.Unit{ID: unit.ID, Data: []byte("updated data"), CreatedAt: unit.CreatedAt}
+	unitsMock.On("Update", mock.Anything, unit.ID, written.Data).Return(written, nil)
+	_, err = testCache.Update(context.Background(), unit.ID, written.Data)
+	require.NoError(t, err)
+	testCache.addRead(start, unit)
+	testCache.removed.End(start)
+
+	require.Equal(t, written, testCache.getByID(context.Background(), unit.ID))
+}
+
 func Test_Delete(t *testing.T) {
 	unitsMock := &mocks.Units{}
 	testCache, err := NewCache(unitsMock, 10)
@@ -70,7 +93,7 @@ func Test_Delete(t *testing.T) {
 	err = testCache.Delete(ctx, unit.ID)
 	require.NoError(t, err)
 
-	chachedUnit := testCache.getByID(unit.ID)
+	chachedUnit := testCache.getByID(context.Background(), unit.ID)
 	require.Nil(t, chachedUnit)
 }
 
@@ -117,6 +140,42 @@ func Test_FindByIDs(t *testing.T) {
 	require.Equal(t, append(units, dbUnit), actualUnits)
 }
 
+func Test_NegativeCache(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	negative, err := units.NewNegativeCache(10, time.Minute)
+	require.NoError(t, err)
+	testCache, err := NewCache(unitsMock, 10, WithNegativeCache(negative))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	missing := randomUnit()
+
+	unitsMock.On("FindByIDs", mock.Anything, []string{missing.ID}).Return(models.Units{}, nil).Once()
+	for i := 0; i < 2; i++ {
+		actualUnits, err := testCache.FindByIDs(ctx, []string{missing.ID})
+		require.NoError(t, err)
+		require.Empty(t, actualUnits)
+
+		actualUnit, err := testCache.FindByID(ctx, missing.ID)
+		require.ErrorIs(t, err, units.ErrNotFound)
+		require.Nil(t, actualUnit)
+	}
+	unitsMock.AssertNumberOfCalls(t, "FindByIDs", 1)
+	unitsMock.AssertNumberOfCalls(t, "FindByID", 0)
+	require.Equal(t, units.NegativeStats{Entries: 1, Hits: 3, Misses: 1}, testCache.NegativeStats())
+
+	// creating the unit forgets that it's missing
+	unitsMock.On("Create", mock.Anything, missing).Return(nil)
+	err = testCache.Create(ctx, missing)
+	require.NoError(t, err)
+	require.Equal(t, 0, testCache.NegativeStats().Entries)
+
+	actualUnit, err := testCache.FindByID(ctx, missing.ID)
+	require.NoError(t, err)
+	require.Equal(t, missing, actualUnit)
+}
+
 func Test_FetchAll(t *testing.T) {
 	unitsMock := &mocks.Units{}
 	testCache, err := NewCache(unitsMock, 10)
@@ -136,10 +195,116 @@ func Test_FetchAll(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, units, actualUnits)
 
-	chachedUnits := testCache.getByIDs([]string{units[0].ID, units[1].ID, units[2].ID})
+	chachedUnits := testCache.getByIDs(context.Background(), []string{units[0].ID, units[1].ID, units[2].ID})
 	require.Equal(t, units, models.Units(chachedUnits))
 }
 
+func Test_FetchAll_Reconcile(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	kept, vanished, notCached := randomUnit(), randomUnit(), randomUnit()
+	testCache.add(kept, vanished)
+
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{kept, notCached}, nil).Once()
+	_, err = testCache.FetchAll(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, testCache.Len())
+	require.Nil(t, testCache.getByID(context.Background(), vanished.ID))
+	require.Equal(t, units.Reconciliation{Removed: 1}, testCache.LastReconciliation())
+
+	// an empty result doesn't wipe the cache
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{}, nil).Once()
+	_, err = testCache.FetchAll(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, testCache.Len())
+	require.Equal(t, units.Reconciliation{Skipped: true}, testCache.LastReconciliation())
+}
+
+func Test_FetchAll_Reconcile_FewCached(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	all := make(models.Units, 0, 10)
+	for i := 0; i < 10; i++ {
+		all = append(all, randomUnit())
+	}
+	testCache.add(all...)
+	unitsMock.On("FetchAll", mock.Anything).Return(all, nil)
+	_, err = testCache.FetchAll(ctx)
+	require.NoError(t, err)
+
+	// the fetch is as large as before, however few of its units are cached
+	vanished := randomUnit()
+	testCache.InvalidateAll()
+	testCache.add(all[0], vanished)
+	_, err = testCache.FetchAll(ctx)
+	require.NoError(t, err)
+
+	require.Nil(t, testCache.getByID(context.Background(), vanished.ID))
+	require.Equal(t, units.Reconciliation{Removed: 1}, testCache.LastReconciliation())
+}
+
+func Test_Invalidate(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10)
+	require.NoError(t, err)
+
+	units := models.Units{
+		randomUnit(),
+		randomUnit(),
+		randomUnit(),
+	}
+	testCache.add(units...)
+	require.Equal(t, 3, testCache.Len())
+
+	testCache.Invalidate(units[0].ID, units[1].ID)
+	require.Equal(t, 1, testCache.Len())
+	require.Nil(t, testCache.getByID(context.Background(), units[0].ID))
+	require.Equal(t, units[2], testCache.getByID(context.Background(), units[2].ID))
+
+	testCache.InvalidateAll()
+	require.Equal(t, 0, testCache.Len())
+}
+
+func Test_FetchChanges(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	cached, deleted, notCached := randomUnit(), randomUnit(), randomUnit()
+	testCache.add(cached, deleted)
+
+	updated := *cached
+	updated.Data = []byte("updated data")
+	updated.UpdatedAt = now
+
+	changes := &models.Changes{
+		Updated:   models.Units{&updated, notCached},
+		Deleted:   []string{deleted.ID},
+		Watermark: now,
+	}
+	unitsMock.On("FetchChanges", mock.Anything, now).Return(changes, nil)
+	actualChanges, err := testCache.FetchChanges(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, changes, actualChanges)
+
+	require.Equal(t, &updated, testCache.getByID(context.Background(), cached.ID))
+	require.Nil(t, testCache.getByID(context.Background(), deleted.ID))
+	require.Nil(t, testCache.getByID(context.Background(), notCached.ID))
+}
+
 func randomUnit() *models.Unit {
 	buf := make([]byte, 50)
 	rand.Read(buf)
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+)
+
+// weightedLRU is an lru of units bounded by the number of entries and,
+// optionally, by total bytes of their ids and data.
+type weightedLRU struct {
+	mu sync.Mutex
+
+	maxEntries int
+	// maxBytes is the current byte budget, 0 means no budget.
+	maxBytes int64
+	// maxEntryBytes is the size of the largest unit admitted, 0 means no limit.
+	maxEntryBytes int64
+
+	// ttl is the time units are fresh for, 0 means forever. Units older than
+	// ttl are returned as stale for staleTTL more.
+	ttl      time.Duration
+	staleTTL time.Duration
+	now      func() time.Time
+
+	bytes int64
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	id      string
+	unit    *models.Unit
+	size    int64
+	addedAt time.Time
+}
+
+func newWeightedLRU(maxEntries int) (*weightedLRU, error) {
+	if maxEntries <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	return &weightedLRU{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}, nil
+}
+
+func (l *weightedLRU) setTTL(ttl, staleTTL time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ttl, l.staleTTL = ttl, staleTTL
+}
+
+func unitSize(unit *models.Unit) int64 {
+	return int64(len(unit.ID) + len(unit.Data))
+}
+
+// Add saves unit as the most recently used one. Units larger than
+// maxEntryBytes are not admitted, a previous version of them is removed.
+func (l *weightedLRU) Add(id string, unit *models.Unit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	size := unitSize(unit)
+	if l.maxEntryBytes > 0 && size > l.maxEntryBytes {
+		l.remove(id)
+		return
+	}
+
+	var addedAt time.Time
+	if l.ttl > 0 {
+		addedAt = l.now()
+	}
+	if el, ok := l.items[id]; ok {
+		e := el.Value.(*lruEntry)
+		l.bytes += size - e.size
+		e.unit, e.size, e.addedAt = unit, size, addedAt
+		l.ll.MoveToFront(el)
+	} else {
+		l.items[id] = l.ll.PushFront(&lruEntry{id: id, unit: unit, size: size, addedAt: addedAt})
+		l.bytes += size
+	}
+	l.evict()
+}
+
+// Get returns the unit and makes it the most recently used one.
+func (l *weightedLRU) Get(id string) (*models.Unit, bool) {
+	unit, _, ok := l.GetStale(id)
+	return unit, ok
+}
+
+// GetStale is Get that reports whether the unit is older than ttl.
+// Expired units are removed.
+func (l *weightedLRU) GetStale(id string) (unit *models.Unit, stale bool, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[id]
+	if !ok {
+		return nil, false, false
+	}
+
+	e := el.Value.(*lruEntry)
+	if l.ttl > 0 {
+		age := l.now().Sub(e.addedAt)
+		if age > l.ttl+l.staleTTL {
+			l.remove(id)
+			return nil, false, false
+		}
+		stale = age > l.ttl
+	}
+	l.ll.MoveToFront(el)
+	return e.unit, stale, true
+}
+
+// Peek returns the unit without updating its recentness.
+func (l *weightedLRU) Peek(id string) (*models.Unit, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[id]; ok {
+		return el.Value.(*lruEntry).unit, true
+	}
+	return nil, false
+}
+
+func (l *weightedLRU) Contains(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.items[id]
+	return ok
+}
+
+func (l *weightedLRU) Remove(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.remove(id)
+}
+
+func (l *weightedLRU) Purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+	l.bytes = 0
+}
+
+// Keys returns ids from the least recently used one.
+func (l *weightedLRU) Keys() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	keys := make([]string, 0, len(l.items))
+	for el := l.ll.Back(); el != nil; el = el.Prev() {
+		keys = append(keys, el.Value.(*lruEntry).id)
+	}
+	return keys
+}
+
+func (l *weightedLRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+func (l *weightedLRU) Bytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bytes
+}
+
+// SetMaxBytes changes the byte budget and evicts units that don't fit.
+func (l *weightedLRU) SetMaxBytes(maxBytes, maxEntryBytes int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxBytes, l.maxEntryBytes = maxBytes, maxEntryBytes
+	l.evict()
+}
+
+func (l *weightedLRU) MaxBytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.maxBytes
+}
+
+// remove must be called under the lock.
+func (l *weightedLRU) remove(id string) bool {
+	el, ok := l.items[id]
+	if !ok {
+		return false
+	}
+	l.ll.Remove(el)
+	delete(l.items, id)
+	l.bytes -= el.Value.(*lruEntry).size
+	return true
+}
+
+// evict drops least recently used units until both limits are met,
+// must be called under the lock.
+func (l *weightedLRU) evict() {
+	for len(l.items) > l.maxEntries || (l.maxBytes > 0 && l.bytes > l.maxBytes) {
+		l.remove(l.ll.Back().Value.(*lruEntry).id)
+	}
+}
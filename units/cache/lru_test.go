@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/stretchr/testify/require"
+)
+
+func sizedUnit(id string, dataSize int) *models.Unit {
+	return &models.Unit{ID: id, Data: make([]byte, dataSize)}
+}
+
+func Test_weightedLRU_MaxBytes(t *testing.T) {
+	l, err := newWeightedLRU(10)
+	require.NoError(t, err)
+	l.SetMaxBytes(300, 0)
+
+	l.Add("1", sizedUnit("1", 99))
+	l.Add("2", sizedUnit("2", 99))
+	l.Add("3", sizedUnit("3", 99))
+	require.Equal(t, int64(300), l.Bytes())
+
+	// "1" becomes the most recently used one, "2" is evicted
+	_, ok := l.Get("1")
+	require.True(t, ok)
+	l.Add("4", sizedUnit("4", 99))
+	require.Equal(t, []string{"3", "1", "4"}, l.Keys())
+	require.Equal(t, int64(300), l.Bytes())
+
+	// replacing a unit accounts the difference
+	l.Add("4", sizedUnit("4", 9))
+	require.Equal(t, int64(210), l.Bytes())
+
+	l.SetMaxBytes(150, 0)
+	require.Equal(t, []string{"1", "4"}, l.Keys())
+	require.Equal(t, int64(110), l.Bytes())
+}
+
+func Test_weightedLRU_MaxEntryBytes(t *testing.T) {
+	l, err := newWeightedLRU(10)
+	require.NoError(t, err)
+	l.SetMaxBytes(1000, 100)
+
+	l.Add("1", sizedUnit("1", 99))
+	l.Add("2", sizedUnit("2", 99))
+
+	// a huge unit doesn't flush others and its old version is dropped
+	l.Add("1", sizedUnit("1", 500))
+	require.Equal(t, []string{"2"}, l.Keys())
+	require.Equal(t, int64(100), l.Bytes())
+}
+
+func Test_weightedLRU_MaxEntries(t *testing.T) {
+	l, err := newWeightedLRU(2)
+	require.NoError(t, err)
+
+	l.Add("1", sizedUnit("1", 1))
+	l.Add("2", sizedUnit("2", 1))
+	l.Add("3", sizedUnit("3", 1))
+	require.Equal(t, []string{"2", "3"}, l.Keys())
+
+	require.True(t, l.Remove("2"))
+	require.False(t, l.Remove("2"))
+	require.Equal(t, int64(2), l.Bytes())
+
+	_, err = newWeightedLRU(0)
+	require.Error(t, err)
+}
+
+func Test_adapt(t *testing.T) {
+	testCache, err := NewCache(nil, 100, WithMaxBytes(1000, 100), WithHeapLimit(10000))
+	require.NoError(t, err)
+
+	budget, changed := testCache.adapt(8000)
+	require.False(t, changed)
+	require.Equal(t, int64(1000), budget)
+
+	for i := 0; i < 20; i++ {
+		budget, _ = testCache.adapt(9500)
+	}
+	require.Equal(t, int64(100), budget)
+
+	for i := 0; i < 50; i++ {
+		budget, _ = testCache.adapt(1000)
+	}
+	require.Equal(t, int64(1000), budget)
+	require.Equal(t, int64(1000), testCache.cache.MaxBytes())
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/AltMax/art-test/models"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Policy is the eviction policy of the cache.
+type Policy string
+
+const (
+	// LRU evicts the least recently used units.
+	LRU Policy = "lru"
+	// TwoQueue keeps units used once apart from frequently used ones,
+	// so full scans don't flush the hot working set.
+	TwoQueue Policy = "2q"
+	// ARC balances between recently and frequently used units adaptively.
+	ARC Policy = "arc"
+	// TTL is LRU with an expiration time of units, see WithTTL.
+	TTL Policy = "ttl"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch policy := Policy(s); policy {
+	case LRU, TwoQueue, ARC, TTL:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown cache policy %q", s)
+	}
+}
+
+// policy is a bounded set of units with its own eviction strategy.
+type policy interface {
+	Add(id string, unit *models.Unit)
+	Get(id string) (*models.Unit, bool)
+	// Peek returns the unit without updating its recentness or frequency.
+	Peek(id string) (*models.Unit, bool)
+	Contains(id string) bool
+	Remove(id string) bool
+	Purge()
+	Keys() []string
+	Len() int
+}
+
+// stalePolicy is a policy keeping units older than their ttl, see WithTTL.
+type stalePolicy interface {
+	policy
+	GetStale(id string) (unit *models.Unit, stale bool, ok bool)
+}
+
+// budgetedPolicy is a policy that can be bounded by bytes.
+type budgetedPolicy interface {
+	policy
+	Bytes() int64
+	SetMaxBytes(maxBytes, maxEntryBytes int64)
+	MaxBytes() int64
+}
+
+func (c *Cache) newPolicy(size int) (*instrumented, error) {
+	var p policy
+	switch c.policy {
+	case LRU, TTL:
+		l, err := newWeightedLRU(size)
+		if err != nil {
+			return nil, err
+		}
+		if c.policy == TTL {
+			if c.ttl <= 0 || c.staleTTL < 0 {
+				return nil, errors.New("cache policy ttl requires a positive ttl and a non-negative stale ttl")
+			}
+			l.setTTL(c.ttl, c.staleTTL)
+		}
+		l.SetMaxBytes(c.maxBytes, c.maxEntryBytes)
+		p = l
+	case TwoQueue:
+		q, err := lru.New2Q[string, *models.Unit](size)
+		if err != nil {
+			return nil, err
+		}
+		p = countedPolicy{q}
+	case ARC:
+		a, err := lru.NewARC[string, *models.Unit](size)
+		if err != nil {
+			return nil, err
+		}
+		p = countedPolicy{a}
+	default:
+		return nil, fmt.Errorf("unknown cache policy %q", c.policy)
+	}
+
+	if _, ok := p.(budgetedPolicy); !ok && c.maxBytes > 0 {
+		return nil, fmt.Errorf("cache policy %q doesn't support a byte budget", c.policy)
+	}
+	return &instrumented{policy: p}, nil
+}
+
+// countedPolicy adapts caches of golang-lru, they are bounded by the number
+// of units only.
+type countedPolicy struct {
+	libCache interface {
+		Add(key string, value *models.Unit)
+		Get(key string) (*models.Unit, bool)
+		Peek(key string) (*models.Unit, bool)
+		Contains(key string) bool
+		Remove(key string)
+		Purge()
+		Keys() []string
+		Len() int
+	}
+}
+
+func (p countedPolicy) Add(id string, unit *models.Unit) {
+	p.libCache.Add(id, unit)
+}
+
+func (p countedPolicy) Get(id string) (*models.Unit, bool) {
+	return p.libCache.Get(id)
+}
+
+func (p countedPolicy) Peek(id string) (*models.Unit, bool) {
+	return p.libCache.Peek(id)
+}
+
+func (p countedPolicy) Contains(id string) bool {
+	return p.libCache.Contains(id)
+}
+
+func (p countedPolicy) Purge() {
+	p.libCache.Purge()
+}
+
+func (p countedPolicy) Keys() []string {
+	return p.libCache.Keys()
+}
+
+func (p countedPolicy) Len() int {
+	return p.libCache.Len()
+}
+
+func (p countedPolicy) Remove(id string) bool {
+	ok := p.libCache.Contains(id)
+	p.libCache.Remove(id)
+	return ok
+}
+
+// instrumented counts hits and misses of a policy.
+type instrumented struct {
+	policy
+	hits   uint64
+	misses uint64
+}
+
+func (p *instrumented) Get(id string) (*models.Unit, bool) {
+	unit, _, ok := p.GetStale(id)
+	return unit, ok
+}
+
+// GetStale is Get that reports whether the unit is older than its ttl,
+// units of policies without a ttl are never stale.
+func (p *instrumented) GetStale(id string) (unit *models.Unit, stale bool, ok bool) {
+	if s, isStale := p.policy.(stalePolicy); isStale {
+		unit, stale, ok = s.GetStale(id)
+	} else {
+		unit, ok = p.policy.Get(id)
+	}
+	if ok {
+		atomic.AddUint64(&p.hits, 1)
+	} else {
+		atomic.AddUint64(&p.misses, 1)
+	}
+	return unit, stale, ok
+}
+
+func (p *instrumented) Bytes() int64 {
+	if b, ok := p.policy.(budgetedPolicy); ok {
+		return b.Bytes()
+	}
+	return 0
+}
+
+func (p *instrumented) SetMaxBytes(maxBytes, maxEntryBytes int64) {
+	if b, ok := p.policy.(budgetedPolicy); ok {
+		b.SetMaxBytes(maxBytes, maxEntryBytes)
+	}
+}
+
+func (p *instrumented) MaxBytes() int64 {
+	if b, ok := p.policy.(budgetedPolicy); ok {
+		return b.MaxBytes()
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Policies(t *testing.T) {
+	for _, policy := range []Policy{LRU, TwoQueue, ARC, TTL} {
+		t.Run(string(policy), func(t *testing.T) {
+			testCache, err := NewCache(&mocks.Units{}, 2, WithPolicy(policy), WithTTL(time.Hour, 0))
+			require.NoError(t, err)
+
+			units := models.Units{randomUnit(), randomUnit(), randomUnit()}
+			testCache.add(units...)
+			require.Equal(t, 2, testCache.Len())
+
+			require.Equal(t, units[2], testCache.getByID(context.Background(), units[2].ID))
+			require.Nil(t, testCache.getByID(context.Background(), units[0].ID))
+
+			testCache.Invalidate(units[2].ID)
+			require.Nil(t, testCache.getByID(context.Background(), units[2].ID))
+
+			stats := testCache.Stats()
+			require.Equal(t, policy, stats.Policy)
+			require.Equal(t, uint64(1), stats.Hits)
+			require.Equal(t, uint64(2), stats.Misses)
+		})
+	}
+}
+
+func Test_Policy_MaxBytes(t *testing.T) {
+	_, err := NewCache(&mocks.Units{}, 2, WithPolicy(ARC), WithMaxBytes(100, 0))
+	require.Error(t, err)
+
+	_, err = ParsePolicy("mru")
+	require.Error(t, err)
+}
+
+func Test_TTL_StaleWhileRevalidate(t *testing.T) {
+	_, err := NewCache(&mocks.Units{}, 10, WithPolicy(TTL))
+	require.Error(t, err)
+
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10, WithPolicy(TTL), WithTTL(time.Minute, time.Minute))
+	require.NoError(t, err)
+
+	l := testCache.cache.policy.(*weightedLRU)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	unit := randomUnit()
+	testCache.add(unit)
+
+	refreshed := *unit
+	refreshed.Data = []byte("refreshed data")
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(&refreshed, nil).Once()
+
+	// a stale unit is returned and refreshed in background
+	now = now.Add(90 * time.Second)
+	require.Equal(t, unit, testCache.getByID(context.Background(), unit.ID))
+	require.Eventually(t, func() bool {
+		return testCache.Stats().Revalidations == 1
+	}, time.Second, time.Millisecond)
+
+	actualUnit, err := testCache.FindByID(context.Background(), unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, &refreshed, actualUnit)
+
+	// an expired unit is a miss
+	now = now.Add(3 * time.Minute)
+	require.Nil(t, testCache.getByID(context.Background(), unit.ID))
+	require.Equal(t, 0, testCache.Len())
+}
+
+func Test_TTL_RevalidateDeleted(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, 10, WithPolicy(TTL), WithTTL(time.Minute, time.Minute))
+	require.NoError(t, err)
+
+	l := testCache.cache.policy.(*weightedLRU)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	unit := randomUnit()
+	testCache.add(unit)
+
+	release := make(chan struct{})
+	unitsMock.On("FindByID", mock.Anything, unit.ID).
+		Run(func(mock.Arguments) { <-release }).
+		Return(unit, nil).Once()
+	unitsMock.On("Delete", mock.Anything, unit.ID).Return(nil).Once()
+
+	// the unit is deleted while it's revalidated, the read version is dropped
+	now = now.Add(90 * time.Second)
+	require.Equal(t, unit, testCache.getByID(context.Background(), unit.ID))
+	require.NoError(t, testCache.Delete(context.Background(), unit.ID))
+	close(release)
+	require.Eventually(t, func() bool {
+		return testCache.Stats().Revalidations == 1
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 0, testCache.Len())
+}
+
+// Benchmark_Policies compares hit ratios of policies on a zipf distributed
+// working set interrupted by full scans of all units, like full syncs do.
+func Benchmark_Policies(b *testing.B) {
+	const (
+		total     = 10000
+		cacheSize = 1000
+		scanEvery = 20000
+	)
+	units := make(models.Units, total)
+	for i := range units {
+		units[i] = &models.Unit{ID: strconv.Itoa(i), Data: make([]byte, 50)}
+	}
+
+	for _, policy := range []Policy{LRU, TwoQueue, ARC, TTL} {
+		b.Run(string(policy), func(b *testing.B) {
+			testCache, err := NewCache(&mocks.Units{}, cacheSize, WithPolicy(policy), WithTTL(time.Hour, 0))
+			require.NoError(b, err)
+			zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, total-1)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%scanEvery == 0 {
+					for _, unit := range units {
+						if testCache.getByID(context.Background(), unit.ID) == nil {
+							testCache.add(unit)
+						}
+					}
+				}
+				unit := units[zipf.Uint64()]
+				if testCache.getByID(context.Background(), unit.ID) == nil {
+					testCache.add(unit)
+				}
+			}
+			b.StopTimer()
+
+			stats := testCache.Stats()
+			b.ReportMetric(float64(stats.Hits)/float64(stats.Hits+stats.Misses), "hit-ratio")
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"runtime/metrics"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	heapMetric = "/memory/classes/heap/objects:bytes"
+
+	// the budget shrinks when the heap is above shrinkAbove of the heap limit
+	// and grows back to the configured one when the heap is below growBelow
+	shrinkAbove  = 0.9
+	growBelow    = 0.7
+	shrinkFactor = 0.75
+	growFactor   = 1.1
+	// minBudgetShare is the smallest share of the configured budget kept
+	minBudgetShare = 0.1
+)
+
+// AdaptToMemoryPressure checks the go heap every interval and adapts
+// the byte budget of the cache to it until ctx is done. It does nothing
+// unless the cache has a byte budget and a heap limit.
+func (c *Cache) AdaptToMemoryPressure(ctx context.Context, interval time.Duration) {
+	if c.heapLimit == 0 || c.maxBytes <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	sample := []metrics.Sample{{Name: heapMetric}}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			metrics.Read(sample)
+			heap := sample[0].Value.Uint64()
+			if budget, changed := c.adapt(heap); changed {
+				zerolog.Ctx(ctx).Info().
+					Uint64("heap", heap).
+					Int64("budget", budget).
+					Msg("cache byte budget adapted to memory pressure")
+			}
+		}
+	}
+}
+
+// adapt shrinks the byte budget when heap approaches the heap limit
+// and grows it back when the pressure is gone.
+func (c *Cache) adapt(heap uint64) (budget int64, changed bool) {
+	current := c.cache.MaxBytes()
+	switch {
+	case float64(heap) > shrinkAbove*float64(c.heapLimit):
+		budget = int64(float64(current) * shrinkFactor)
+		if min := int64(float64(c.maxBytes) * minBudgetShare); budget < min {
+			budget = min
+		}
+	case float64(heap) < growBelow*float64(c.heapLimit):
+		budget = int64(float64(current) * growFactor)
+		if budget > c.maxBytes {
+			budget = c.maxBytes
+		}
+	default:
+		return current, false
+	}
+
+	if budget == current {
+		return current, false
+	}
+	c.cache.SetMaxBytes(budget, c.maxEntryBytes)
+	return budget, true
+}
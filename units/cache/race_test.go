@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/AltMax/art-test/units/racetest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LostUpdates(t *testing.T) {
+	for _, policy := range []Policy{LRU, TwoQueue, ARC, TTL} {
+		t.Run(string(policy), func(t *testing.T) {
+			racetest.Run(t,
+				func(next *mocks.Units) units.Units {
+					negative, err := units.NewNegativeCache(10, time.Hour)
+					require.NoError(t, err)
+					c, err := NewCache(next, 10, WithPolicy(policy), WithTTL(time.Hour, 0), WithNegativeCache(negative))
+					require.NoError(t, err)
+					return c
+				},
+				func(layer units.Units, id string) *models.Unit {
+					unit, _ := layer.(*Cache).cache.Peek(id)
+					return unit
+				},
+			)
+		})
+	}
+}
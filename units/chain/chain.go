@@ -0,0 +1,171 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+)
+
+// Source creates the innermost layer that reads and writes the database.
+type Source func() (units.Units, error)
+
+// Decorator creates a layer over next. It returns an error when next
+// is not a layer it can be placed over.
+type Decorator func(next units.Units) (units.Units, error)
+
+// Builder assembles a chain of layers from their names.
+type Builder struct {
+	sources    map[string]Source
+	decorators map[string]Decorator
+	wrap       func(name string, layer units.Units) units.Units
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		sources:    make(map[string]Source),
+		decorators: make(map[string]Decorator),
+	}
+}
+
+// Source registers a layer that can only be the first one.
+func (b *Builder) Source(name string, source Source) *Builder {
+	b.sources[name] = source
+	return b
+}
+
+// Decorator registers a layer that is placed over the previous one.
+func (b *Builder) Decorator(name string, decorator Decorator) *Builder {
+	b.decorators[name] = decorator
+	return b
+}
+
+// Wrap makes every layer wrapped with wrap before the next layer is placed
+// over it. Wrappers implement Unwrap() units.Units, decorators that need
+// a particular layer under them get it with Unwrap.
+func (b *Builder) Wrap(wrap func(name string, layer units.Units) units.Units) *Builder {
+	b.wrap = wrap
+	return b
+}
+
+// Build creates layers in the given order, from the innermost one.
+// The first layer must be a source, every layer is used at most once.
+func (b *Builder) Build(names []string) (*Chain, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no layers")
+	}
+	if _, ok := b.sources[names[0]]; !ok {
+		return nil, fmt.Errorf("the first layer must be one of %s, got %q", b.known(false), names[0])
+	}
+
+	c := &Chain{}
+	seen := make(map[string]struct{}, len(names))
+	for i, name := range names {
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("layer %q is used twice", name)
+		}
+		seen[name] = struct{}{}
+
+		var (
+			layer units.Units
+			err   error
+		)
+		if i == 0 {
+			layer, err = b.sources[name]()
+		} else if decorator, ok := b.decorators[name]; ok {
+			layer, err = decorator(c.Units)
+		} else if _, ok := b.sources[name]; ok {
+			return nil, fmt.Errorf("layer %q can only be the first one", name)
+		} else {
+			return nil, fmt.Errorf("unknown layer %q, known are %s", name, b.known(true))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", name, err)
+		}
+
+		c.layers = append(c.layers, Named{Name: name, Units: layer})
+		if b.wrap != nil {
+			layer = b.wrap(name, layer)
+		}
+		c.Units = layer
+	}
+	return c, nil
+}
+
+// known lists names of registered layers, decorators are listed
+// only when withDecorators is true.
+func (b *Builder) known(withDecorators bool) string {
+	names := make([]string, 0, len(b.sources)+len(b.decorators))
+	for name := range b.sources {
+		names = append(names, name)
+	}
+	if withDecorators {
+		for name := range b.decorators {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Unwrap returns the layer under wrappers, other layers are returned as is.
+func Unwrap(layer units.Units) units.Units {
+	for {
+		wrapper, ok := layer.(interface{ Unwrap() units.Units })
+		if !ok {
+			return layer
+		}
+		layer = wrapper.Unwrap()
+	}
+}
+
+// Named is a layer of a chain with its name, layers are not wrapped.
+type Named struct {
+	Name string
+	units.Units
+}
+
+// Chain is a chain of layers, it's the outermost layer itself
+// with its wrapper if any.
+type Chain struct {
+	units.Units
+	// layers are ordered from the innermost one
+	layers []Named
+}
+
+// Scan streams units through the outermost layer.
+func (c *Chain) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	return units.Scan(ctx, c.Units, fn)
+}
+
+// Layer returns the layer with the name.
+func (c *Chain) Layer(name string) (units.Units, bool) {
+	for _, layer := range c.layers {
+		if layer.Name == name {
+			return layer.Units, true
+		}
+	}
+	return nil, false
+}
+
+// Layers returns layers from the outermost one.
+func (c *Chain) Layers() []Named {
+	layers := make([]Named, 0, len(c.layers))
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		layers = append(layers, c.layers[i])
+	}
+	return layers
+}
+
+// Names returns names of layers from the innermost one, as they are configured.
+func (c *Chain) Names() []string {
+	names := make([]string, 0, len(c.layers))
+	for _, layer := range c.layers {
+		names = append(names, layer.Name)
+	}
+	return names
+}
@@ -0,0 +1,109 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+type decorated struct {
+	units.Units
+	name string
+}
+
+func newTestBuilder() *Builder {
+	decorator := func(name string) Decorator {
+		return func(next units.Units) (units.Units, error) {
+			return &decorated{Units: next, name: name}, nil
+		}
+	}
+	return NewBuilder().
+		Source("dao", func() (units.Units, error) { return &mocks.Units{}, nil }).
+		Source("memory", func() (units.Units, error) { return &mocks.Units{}, nil }).
+		Decorator("store", decorator("store")).
+		Decorator("lru", decorator("lru")).
+		Decorator("picky", func(next units.Units) (units.Units, error) {
+			if _, ok := Unwrap(next).(*decorated); !ok {
+				return nil, errors.New("must be over a decorated layer")
+			}
+			return &decorated{Units: next, name: "picky"}, nil
+		})
+}
+
+func Test_Build(t *testing.T) {
+	c, err := newTestBuilder().Build([]string{"dao", "store", "lru"})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"dao", "store", "lru"}, c.Names())
+	lru, ok := c.Layer("lru")
+	require.True(t, ok)
+	require.Equal(t, lru, c.Units)
+
+	store, ok := c.Layer("store")
+	require.True(t, ok)
+	require.Equal(t, store, lru.(*decorated).Units)
+
+	_, ok = c.Layer("picky")
+	require.False(t, ok)
+
+	layers := c.Layers()
+	require.Len(t, layers, 3)
+	require.Equal(t, "lru", layers[0].Name)
+	require.Equal(t, "dao", layers[2].Name)
+}
+
+func Test_Build_WithoutStore(t *testing.T) {
+	c, err := newTestBuilder().Build([]string{"dao", "lru"})
+	require.NoError(t, err)
+	lru, _ := c.Layer("lru")
+	dao, _ := c.Layer("dao")
+	require.Equal(t, dao, lru.(*decorated).Units)
+}
+
+func Test_Build_Invalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		layers []string
+		err    string
+	}{
+		"empty":            {nil, "no layers"},
+		"no source":        {[]string{"store", "dao"}, `the first layer must be one of dao, memory, got "store"`},
+		"source not first": {[]string{"dao", "store", "memory"}, `layer "memory" can only be the first one`},
+		"duplicate":        {[]string{"dao", "lru", "lru"}, `layer "lru" is used twice`},
+		"unknown":          {[]string{"dao", "tracing"}, `unknown layer "tracing"`},
+		"rejected by next": {[]string{"dao", "picky"}, `layer "picky": must be over a decorated layer`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestBuilder().Build(tc.layers)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+type wrapper struct {
+	units.Units
+}
+
+func (w *wrapper) Unwrap() units.Units {
+	return w.Units
+}
+
+func Test_Build_Wrap(t *testing.T) {
+	wrapped := make([]string, 0)
+	c, err := newTestBuilder().
+		Wrap(func(name string, layer units.Units) units.Units {
+			wrapped = append(wrapped, name)
+			return &wrapper{Units: layer}
+		}).
+		Build([]string{"dao", "store", "picky"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"dao", "store", "picky"}, wrapped)
+
+	// layers are returned unwrapped, the chain is wrapped
+	picky, _ := c.Layer("picky")
+	require.Equal(t, picky, Unwrap(c.Units))
+	store, _ := c.Layer("store")
+	require.Equal(t, store, Unwrap(picky.(*decorated).Units))
+}
@@ -0,0 +1,249 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+)
+
+// Coalescer deduplicates concurrent lookups of the same ids, callers asking
+// for an id that is already being looked up wait for that lookup instead of
+// querying the next layer again.
+type Coalescer struct {
+	units.Units
+
+	mu       sync.Mutex
+	inFlight map[string]*call
+}
+
+// call is a lookup of the next layer shared by its waiters. It runs with
+// a context detached from the caller that started it and without a deadline,
+// the context is canceled when every waiter has left.
+type call struct {
+	ids    []string
+	done   chan struct{}
+	units  map[string]*models.Unit
+	err    error
+	cancel context.CancelFunc
+	// waiters is guarded by Coalescer.mu
+	waiters int
+}
+
+func NewCoalescer(next units.Units) *Coalescer {
+	return &Coalescer{
+		Units:    next,
+		inFlight: make(map[string]*call),
+	}
+}
+
+func (c *Coalescer) Create(ctx context.Context, unit *models.Unit) error {
+	err := c.Units.Create(ctx, unit)
+	c.forget(unit.ID)
+	return err
+}
+
+func (c *Coalescer) Update(ctx context.Context, id string, data []byte) (*models.Unit, error) {
+	unit, err := c.Units.Update(ctx, id, data)
+	c.forget(id)
+	return unit, err
+}
+
+func (c *Coalescer) Delete(ctx context.Context, id string) error {
+	err := c.Units.Delete(ctx, id)
+	c.forget(id)
+	return err
+}
+
+func (c *Coalescer) WriteBatch(ctx context.Context, created, updated models.Units) ([]string, []string, error) {
+	notFound, existing, err := units.WriteBatch(ctx, c.Units, created, updated)
+	for _, id := range append(created.IDs(), updated.IDs()...) {
+		c.forget(id)
+	}
+	return notFound, existing, err
+}
+
+// Scan passes scans through, full fetches are not coalesced.
+func (c *Coalescer) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	return units.Scan(ctx, c.Units, fn)
+}
+
+func (c *Coalescer) FindByID(ctx context.Context, id string) (*models.Unit, error) {
+	c.mu.Lock()
+	cl, ok := c.inFlight[id]
+	if ok {
+		cl.waiters++
+	} else {
+		cl = c.start(ctx, []string{id}, func(ctx context.Context) (models.Units, error) {
+			unit, err := c.Units.FindByID(ctx, id)
+			if errors.Is(err, units.ErrNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return models.Units{unit}, nil
+		})
+	}
+	c.mu.Unlock()
+
+	if err := c.wait(ctx, cl); err != nil {
+		return nil, err
+	}
+	unit, ok := cl.units[id]
+	if !ok {
+		return nil, units.ErrNotFound
+	}
+	return unit, nil
+}
+
+// FindByIDs waits for lookups in flight that cover some of ids
+// and looks up the rest with a single call of the next layer.
+func (c *Coalescer) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
+	calls := make([]*call, 0, 1)
+	joined := make(map[*call]struct{})
+	missed := make([]string, 0, len(ids))
+
+	c.mu.Lock()
+	for _, id := range ids {
+		cl, ok := c.inFlight[id]
+		if !ok {
+			missed = append(missed, id)
+			continue
+		}
+		if _, isJoined := joined[cl]; !isJoined {
+			joined[cl] = struct{}{}
+			cl.waiters++
+			calls = append(calls, cl)
+		}
+	}
+	if len(missed) > 0 {
+		missed = deduplicate(missed)
+		calls = append(calls, c.start(ctx, missed, func(ctx context.Context) (models.Units, error) {
+			return c.Units.FindByIDs(ctx, missed)
+		}))
+	}
+	c.mu.Unlock()
+
+	for i, cl := range calls {
+		if err := c.wait(ctx, cl); err != nil {
+			for _, left := range calls[i+1:] {
+				c.leave(left)
+			}
+			return nil, err
+		}
+	}
+
+	found := make(models.Units, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, isDuplicate := seen[id]; isDuplicate {
+			continue
+		}
+		seen[id] = struct{}{}
+		for _, cl := range calls {
+			if unit, ok := cl.units[id]; ok {
+				found = append(found, unit)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// start registers a call of fn for ids and runs it, must be called under c.mu.
+func (c *Coalescer) start(ctx context.Context, ids []string, fn func(ctx context.Context) (models.Units, error)) *call {
+	callCtx, cancel := detach(ctx)
+	cl := &call{
+		ids:     ids,
+		done:    make(chan struct{}),
+		cancel:  cancel,
+		waiters: 1,
+	}
+	for _, id := range ids {
+		c.inFlight[id] = cl
+	}
+
+	go func() {
+		defer cancel()
+		found, err := run(callCtx, fn)
+
+		c.mu.Lock()
+		c.unregister(cl)
+		c.mu.Unlock()
+
+		cl.units = make(map[string]*models.Unit, len(found))
+		for _, unit := range found {
+			cl.units[unit.ID] = unit
+		}
+		cl.err = err
+		close(cl.done)
+	}()
+
+	return cl
+}
+
+func run(ctx context.Context, fn func(ctx context.Context) (models.Units, error)) (found models.Units, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("coalesced lookup panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// wait returns when cl is done or ctx is canceled,
+// the caller stops waiting for cl in the latter case.
+func (c *Coalescer) wait(ctx context.Context, cl *call) error {
+	select {
+	case <-cl.done:
+		return cl.err
+	case <-ctx.Done():
+		c.leave(cl)
+		return ctx.Err()
+	}
+}
+
+// leave cancels cl when its last waiter has left,
+// so nobody joins a call that is being canceled.
+func (c *Coalescer) leave(cl *call) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl.waiters--
+	if cl.waiters == 0 {
+		c.unregister(cl)
+		cl.cancel()
+	}
+}
+
+// unregister removes cl from calls in flight, must be called under c.mu.
+func (c *Coalescer) unregister(cl *call) {
+	for _, id := range cl.ids {
+		if c.inFlight[id] == cl {
+			delete(c.inFlight, id)
+		}
+	}
+}
+
+// forget makes lookups of id started after a write not join lookups that
+// started before it, so they don't return the unit as it was before the write.
+func (c *Coalescer) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inFlight, id)
+}
+
+func deduplicate(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if _, isDuplicate := seen[id]; !isDuplicate {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,229 @@
+package coalesce
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// waitInFlight waits until lookups of ids are registered as in flight.
+func waitInFlight(t *testing.T, c *Coalescer, ids ...string) {
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, id := range ids {
+			if _, ok := c.inFlight[id]; !ok {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+// waitWaiters waits until the lookup of id has the given number of waiters.
+func waitWaiters(t *testing.T, c *Coalescer, id string, waiters int) {
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		cl, ok := c.inFlight[id]
+		return ok && cl.waiters == waiters
+	}, time.Second, time.Millisecond)
+}
+
+func Test_FindByID_Coalesced(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	coalescer := NewCoalescer(unitsMock)
+
+	ctx := context.Background()
+	unit := randomUnit()
+
+	release := make(chan struct{})
+	unitsMock.On("FindByID", mock.Anything, unit.ID).
+		Run(func(mock.Arguments) { <-release }).
+		Return(unit, nil).Once()
+
+	results := make(chan result, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			actualUnit, err := coalescer.FindByID(ctx, unit.ID)
+			results <- result{actualUnit, err}
+		}()
+	}
+	waitWaiters(t, coalescer, unit.ID, 10)
+	close(release)
+	for i := 0; i < 10; i++ {
+		r := <-results
+		require.NoError(t, r.err)
+		require.Equal(t, unit, r.unit)
+	}
+
+	unitsMock.AssertNumberOfCalls(t, "FindByID", 1)
+}
+
+func Test_FindByID_NotFound(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	coalescer := NewCoalescer(unitsMock)
+
+	id := uuid.New().String()
+	unitsMock.On("FindByID", mock.Anything, id).Return(nil, units.ErrNotFound)
+
+	unit, err := coalescer.FindByID(context.Background(), id)
+	require.ErrorIs(t, err, units.ErrNotFound)
+	require.Nil(t, unit)
+}
+
+func Test_FindByIDs_Overlapping(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	coalescer := NewCoalescer(unitsMock)
+
+	ctx := context.Background()
+	a, b, c := randomUnit(), randomUnit(), randomUnit()
+
+	release := make(chan struct{})
+	unitsMock.On("FindByIDs", mock.Anything, []string{a.ID, b.ID}).
+		Run(func(mock.Arguments) { <-release }).
+		Return(models.Units{a, b}, nil).Once()
+	unitsMock.On("FindByIDs", mock.Anything, []string{c.ID}).Return(models.Units{c}, nil).Once()
+
+	first := make(chan results, 1)
+	go func() {
+		found, err := coalescer.FindByIDs(ctx, []string{a.ID, b.ID})
+		first <- results{found, err}
+	}()
+	waitInFlight(t, coalescer, a.ID, b.ID)
+
+	second := make(chan results, 1)
+	go func() {
+		found, err := coalescer.FindByIDs(ctx, []string{b.ID, c.ID, b.ID})
+		second <- results{found, err}
+	}()
+	waitWaiters(t, coalescer, b.ID, 2)
+	close(release)
+
+	r := <-first
+	require.NoError(t, r.err)
+	require.Equal(t, models.Units{a, b}, r.units)
+	r = <-second
+	require.NoError(t, r.err)
+	require.Equal(t, models.Units{b, c}, r.units)
+}
+
+func Test_FindByID_Canceled(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	coalescer := NewCoalescer(unitsMock)
+
+	unit := randomUnit()
+
+	release := make(chan struct{})
+	unitsMock.On("FindByID", mock.Anything, unit.ID).
+		Run(func(mock.Arguments) { <-release }).
+		Return(unit, nil).Once()
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := coalescer.FindByID(leaderCtx, unit.ID)
+		leaderErr <- err
+	}()
+	waitInFlight(t, coalescer, unit.ID)
+
+	waiter := make(chan result, 1)
+	go func() {
+		actualUnit, err := coalescer.FindByID(context.Background(), unit.ID)
+		waiter <- result{actualUnit, err}
+	}()
+	waitWaiters(t, coalescer, unit.ID, 2)
+
+	// the leader leaves, the lookup goes on for the other waiter
+	cancel()
+	require.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	close(release)
+	r := <-waiter
+	require.NoError(t, r.err)
+	require.Equal(t, unit, r.unit)
+	unitsMock.AssertNumberOfCalls(t, "FindByID", 1)
+}
+
+func Test_FindByID_LeaderDeadline(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	coalescer := NewCoalescer(unitsMock)
+
+	unit := randomUnit()
+
+	release := make(chan struct{})
+	hasDeadline := make(chan bool, 1)
+	unitsMock.On("FindByID", mock.Anything, unit.ID).
+		Run(func(args mock.Arguments) {
+			_, ok := args.Get(0).(context.Context).Deadline()
+			hasDeadline <- ok
+			<-release
+		}).
+		Return(unit, nil).Once()
+
+	leaderCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := coalescer.FindByID(leaderCtx, unit.ID)
+		leaderErr <- err
+	}()
+	waitInFlight(t, coalescer, unit.ID)
+
+	waiterCtx, waiterCancel := context.WithTimeout(context.Background(), time.Minute)
+	defer waiterCancel()
+	waiter := make(chan result, 1)
+	go func() {
+		actualUnit, err := coalescer.FindByID(waiterCtx, unit.ID)
+		waiter <- result{actualUnit, err}
+	}()
+	waitWaiters(t, coalescer, unit.ID, 2)
+
+	// the deadline of the leader does not bound the lookup of the other waiter
+	require.ErrorIs(t, <-leaderErr, context.DeadlineExceeded)
+	require.False(t, <-hasDeadline)
+	close(release)
+	r := <-waiter
+	require.NoError(t, r.err)
+	require.Equal(t, unit, r.unit)
+}
+
+func Test_detach(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+
+	detachedCtx, detachedCancel := detach(ctx)
+	defer detachedCancel()
+	cancel()
+
+	require.NoError(t, detachedCtx.Err())
+	_, ok := detachedCtx.Deadline()
+	require.False(t, ok)
+}
+
+type result struct {
+	unit *models.Unit
+	err  error
+}
+
+type results struct {
+	units models.Units
+	err   error
+}
+
+func randomUnit() *models.Unit {
+	buf := make([]byte, 50)
+	rand.Read(buf)
+	return &models.Unit{
+		ID:        uuid.New().String(),
+		Data:      buf,
+		CreatedAt: time.Now().UTC(),
+	}
+}
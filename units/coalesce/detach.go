@@ -0,0 +1,30 @@
+package coalesce
+
+import (
+	"context"
+	"time"
+)
+
+// detached keeps values of the parent context but not its cancellation.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+// detach returns a context with values of ctx that is neither canceled
+// together with ctx nor bound by its deadline: waiters joining later may
+// wait longer than the caller that started the call.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(detached{ctx})
+}
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/AltMax/art-test/units"
+	"github.com/jackc/pgconn"
+)
+
+// classify maps driver and server errors onto the units error kinds.
+// Errors that are already classified or unknown are returned as is.
+func classify(err error) error {
+	for _, kind := range []error{
+		units.ErrNotFound,
+		units.ErrPreconditionFailed,
+		units.ErrUnavailable,
+		units.ErrDeadlineExceeded,
+	} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return classifyPgError(pgErr, err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return units.WithKind(units.ErrDeadlineExceeded, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || pgconn.SafeToRetry(err) {
+		return units.WithKind(units.ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+func classifyPgError(pgErr *pgconn.PgError, err error) error {
+	switch {
+	case strings.HasPrefix(pgErr.Code, "23"): // integrity_constraint_violation
+		return units.WithKind(units.ErrPreconditionFailed, err)
+	case pgErr.Code == "57014": // query_canceled, statement_timeout included
+		return units.WithKind(units.ErrDeadlineExceeded, err)
+	case pgErr.Code == "40001", pgErr.Code == "40P01": // serialization_failure, deadlock_detected
+		return units.WithKind(units.ErrUnavailable, err)
+	case strings.HasPrefix(pgErr.Code, "08"), // connection_exception
+		strings.HasPrefix(pgErr.Code, "53"),  // insufficient_resources
+		strings.HasPrefix(pgErr.Code, "57P"): // admin_shutdown, crash_shutdown, cannot_connect_now
+		return units.WithKind(units.ErrUnavailable, err)
+	default:
+		return err
+	}
+}
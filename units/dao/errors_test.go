@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/AltMax/art-test/units"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_classify(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{&pgconn.PgError{Code: "23505"}, units.ErrPreconditionFailed},
+		{&pgconn.PgError{Code: "23503"}, units.ErrPreconditionFailed},
+		{&pgconn.PgError{Code: "57014"}, units.ErrDeadlineExceeded},
+		{&pgconn.PgError{Code: "40001"}, units.ErrUnavailable},
+		{&pgconn.PgError{Code: "08006"}, units.ErrUnavailable},
+		{&pgconn.PgError{Code: "53300"}, units.ErrUnavailable},
+		{&pgconn.PgError{Code: "57P01"}, units.ErrUnavailable},
+		{fmt.Errorf("query, %w", context.DeadlineExceeded), units.ErrDeadlineExceeded},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, units.ErrUnavailable},
+		{ErrNotFound, units.ErrNotFound},
+	}
+
+	for _, test := range tests {
+		err := classify(test.err)
+		require.ErrorIs(t, err, test.kind, test.err.Error())
+		require.ErrorIs(t, err, test.err)
+	}
+
+	syntaxErr := &pgconn.PgError{Code: "42601"}
+	require.Equal(t, syntaxErr, classify(syntaxErr))
+}
@@ -4,45 +4,109 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/AltMax/art-test/models"
 	"github.com/AltMax/art-test/postgresql"
+	"github.com/AltMax/art-test/units"
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound = units.ErrNotFound
 
-	selectUnitBuilder = sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("id", "data", "created_at").From("units")
+	selectUnitBuilder = sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("id", "data", "created_at", "updated_at").From("units")
+)
+
+const (
+	defaultFindChunkSize   = 10000
+	defaultFindConcurrency = 4
+	defaultScanBatchSize   = 1000
+	defaultScanPartitions  = 1
 )
 
 type Units struct {
 	db postgresql.DB
+
+	findChunkSize   int
+	findConcurrency int
+	scanBatchSize   int
+	scanPartitions  int
+}
+
+type Option func(*Units)
+
+// WithFindChunks makes FindByIDs query ids by chunks of size,
+// at most concurrency chunks are queried at once.
+func WithFindChunks(size, concurrency int) Option {
+	return func(u *Units) {
+		u.findChunkSize = size
+		u.findConcurrency = concurrency
+	}
+}
+
+// WithScan makes Scan pass units to its callback by batches of batchSize.
+// With partitions above one the ids are split into as many key ranges
+// that are read concurrently on separate connections.
+func WithScan(batchSize, partitions int) Option {
+	return func(u *Units) {
+		u.scanBatchSize = batchSize
+		u.scanPartitions = partitions
+	}
 }
 
-func NewUnits(db postgresql.DB) *Units {
-	return &Units{db: db}
+func NewUnits(db postgresql.DB, opts ...Option) *Units {
+	u := &Units{
+		db:              db,
+		findChunkSize:   defaultFindChunkSize,
+		findConcurrency: defaultFindConcurrency,
+		scanBatchSize:   defaultScanBatchSize,
+		scanPartitions:  defaultScanPartitions,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	if u.findChunkSize <= 0 {
+		u.findChunkSize = defaultFindChunkSize
+	}
+	if u.findConcurrency <= 0 {
+		u.findConcurrency = 1
+	}
+	if u.scanBatchSize <= 0 {
+		u.scanBatchSize = defaultScanBatchSize
+	}
+	if u.scanPartitions <= 0 {
+		u.scanPartitions = defaultScanPartitions
+	}
+	return u
 }
 
 func (u *Units) Create(ctx context.Context, unit *models.Unit) error {
 	const op = "units.Units.Create"
 
-	_, err := u.db.ExecCtx(
-		ctx,
-		`insert into units(
-			id, data, created_at
-		) 
-		values(
-			$1, $2, $3
-		) 
-		on conflict(id) do nothing`,
-		unit.ID, unit.Data, unit.CreatedAt)
-	if err != nil {
+	err := u.run(ctx, func(db postgresql.DB) error {
+		return db.QueryRowCtx(
+			ctx,
+			`insert into units(
+				id, data, created_at
+			) 
+			values(
+				$1, $2, $3
+			) 
+			on conflict(id) do nothing
+			returning updated_at`,
+			unit.ID, unit.Data, unit.CreatedAt).Scan(&unit.UpdatedAt)
+	})
+	switch err {
+	case nil, pgx.ErrNoRows:
+		// no rows means the unit already exists, it's kept as is
+		return nil
+	default:
 		return wrap(op, err)
 	}
-
-	return nil
 }
 
 func (u *Units) Update(ctx context.Context, id string, data []byte) (*models.Unit, error) {
@@ -53,7 +117,9 @@ func (u *Units) Update(ctx context.Context, id string, data []byte) (*models.Uni
 		Data: data,
 	}
 
-	err := u.db.QueryRowCtx(ctx, `update units set data = $2 where id = $1 returning created_at`, unit.ID, unit.Data).Scan(&unit.CreatedAt)
+	err := u.run(ctx, func(db postgresql.DB) error {
+		return db.QueryRowCtx(ctx, `update units set data = $2 where id = $1 returning created_at, updated_at`, unit.ID, unit.Data).Scan(&unit.CreatedAt, &unit.UpdatedAt)
+	})
 	switch err {
 	case pgx.ErrNoRows:
 		return nil, ErrNotFound
@@ -64,10 +130,57 @@ func (u *Units) Update(ctx context.Context, id string, data []byte) (*models.Uni
 	}
 }
 
+// WriteBatch creates and updates units with a single round trip. Existing
+// units of created are kept as is like in Create.
+func (u *Units) WriteBatch(ctx context.Context, created, updated models.Units) ([]string, []string, error) {
+	const op = "units.Units.WriteBatch"
+
+	batch := &pgx.Batch{}
+	for _, unit := range created {
+		batch.Queue(`insert into units(id, data, created_at) values($1, $2, $3) on conflict(id) do nothing returning updated_at`, unit.ID, unit.Data, unit.CreatedAt)
+	}
+	for _, unit := range updated {
+		batch.Queue(`update units set data = $2 where id = $1 returning updated_at`, unit.ID, unit.Data)
+	}
+
+	notFound, existing := make([]string, 0), make([]string, 0)
+	err := u.run(ctx, func(db postgresql.DB) error {
+		results := db.SendBatch(ctx, batch)
+		defer results.Close()
+
+		for _, unit := range created {
+			err := results.QueryRow().Scan(&unit.UpdatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				existing = append(existing, unit.ID)
+			} else if err != nil {
+				return err
+			}
+		}
+		for _, unit := range updated {
+			err := results.QueryRow().Scan(&unit.UpdatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				notFound = append(notFound, unit.ID)
+			} else if err != nil {
+				return err
+			}
+		}
+		return results.Close()
+	})
+	if err != nil {
+		return nil, nil, wrap(op, err)
+	}
+
+	return notFound, existing, nil
+}
+
 func (u *Units) Delete(ctx context.Context, id string) error {
 	const op = "units.Units.Delete"
 
-	tag, err := u.db.ExecCtx(ctx, `delete from units where id = $1`, id)
+	var tag pgconn.CommandTag
+	err := u.run(ctx, func(db postgresql.DB) (err error) {
+		tag, err = db.ExecCtx(ctx, `delete from units where id = $1`, id)
+		return err
+	})
 	if err != nil {
 		return wrap(op, err)
 	}
@@ -81,7 +194,7 @@ func (u *Units) Delete(ctx context.Context, id string) error {
 
 func (u *Units) FindByID(ctx context.Context, id string) (*models.Unit, error) {
 	const op = "units.Units.FindByID"
-	units, err := u.queryUnits(ctx, selectUnitBuilder.Where(sq.Eq{"id": id}))
+	units, err := u.queryUnits(ctx, selectUnitBuilder.Where(sq.Eq{"id": id}), 1)
 	if err != nil {
 		return nil, wrap(op, err)
 	}
@@ -91,27 +204,325 @@ func (u *Units) FindByID(ctx context.Context, id string) (*models.Unit, error) {
 	return units[0], nil
 }
 
+// FindByIDs passes ids as a single array, so the query has the same plan
+// for any number of ids. Lists longer than the chunk size are split into
+// chunks queried concurrently, units are returned in the order of chunks.
 func (u *Units) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
 	const op = "units.Units.FindByIDs"
-	units, err := u.queryUnits(ctx, selectUnitBuilder.Where(sq.Eq{"id": ids}))
-	if err != nil {
+	if len(ids) <= u.findChunkSize {
+		units, err := u.queryUnits(ctx, selectByIDs(ids), len(ids))
+		if err != nil {
+			return nil, wrap(op, err)
+		}
+		return units, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks := chunk(ids, u.findChunkSize)
+	found := make([]models.Units, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, u.findConcurrency)
+	var wg sync.WaitGroup
+	for i := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+		if errs[i] != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			found[i], errs[i] = u.queryUnits(ctx, selectByIDs(chunks[i]), len(chunks[i]))
+			if errs[i] != nil {
+				// the other chunks are useless without this one
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := firstError(errs); err != nil {
 		return nil, wrap(op, err)
 	}
+	units := make(models.Units, 0, len(ids))
+	for _, chunk := range found {
+		units = append(units, chunk...)
+	}
 	return units, nil
 }
 
 func (u *Units) FetchAll(ctx context.Context) (models.Units, error) {
 	const op = "units.Units.FetchAll"
-	units, err := u.queryUnits(ctx, selectUnitBuilder)
+	units := make(models.Units, 0)
+	err := u.scan(ctx, u.readScan, func(batch models.Units) error {
+		units = append(units, batch...)
+		return nil
+	})
 	if err != nil {
 		return nil, wrap(op, err)
 	}
 	return units, nil
 }
 
-func (u *Units) queryUnits(ctx context.Context, builder sq.SelectBuilder) (models.Units, error) {
+// Scan reads all units and passes them to fn by batches as rows arrive.
+// Partitions are read concurrently, fn is called under a lock. Layers remove
+// units missing from a scan, so unlike FetchAll it reads from the primary:
+// a replica doesn't have units created within its lag yet.
+func (u *Units) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	const op = "units.Units.Scan"
+	return wrap(op, u.scan(ctx, u.runScan, fn))
+}
+
+// scan reads all units by queries run by run.
+func (u *Units) scan(ctx context.Context, run runner, fn func(batch models.Units) error) error {
+	ranges, err := u.keyRanges(ctx, run)
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 1 {
+		return u.scanRange(ctx, run, ranges[0], fn)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		fnMu sync.Mutex
+		wg   sync.WaitGroup
+	)
+	errs := make([]error, len(ranges))
+	for i := range ranges {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = u.scanRange(ctx, run, ranges[i], func(batch models.Units) error {
+				fnMu.Lock()
+				defer fnMu.Unlock()
+				return fn(batch)
+			})
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return firstError(errs)
+}
+
+// keyRanges splits ids into scanPartitions ranges by quantiles of ids.
+// Small tables may have fewer distinct bounds, so fewer ranges are returned.
+func (u *Units) keyRanges(ctx context.Context, run runner) ([]sq.Sqlizer, error) {
+	if u.scanPartitions == 1 {
+		return []sq.Sqlizer{sq.Expr("true")}, nil
+	}
+
+	fractions := make([]float64, 0, u.scanPartitions-1)
+	for i := 1; i < u.scanPartitions; i++ {
+		fractions = append(fractions, float64(i)/float64(u.scanPartitions))
+	}
+	var bounds []string
+	err := run(ctx, func(db postgresql.DB) error {
+		return db.QueryRowCtx(ctx,
+			`select coalesce(percentile_disc($1::float8[]) within group (order by id), '{}') from units`,
+			fractions).Scan(&bounds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]sq.Sqlizer, 0, len(bounds)+1)
+	var lower string
+	for i, bound := range bounds {
+		if i > 0 && bound == lower {
+			continue
+		}
+		if i == 0 {
+			ranges = append(ranges, sq.Lt{"id": bound})
+		} else {
+			ranges = append(ranges, sq.And{sq.GtOrEq{"id": lower}, sq.Lt{"id": bound}})
+		}
+		lower = bound
+	}
+	if len(ranges) == 0 {
+		return []sq.Sqlizer{sq.Expr("true")}, nil
+	}
+	return append(ranges, sq.GtOrEq{"id": lower}), nil
+}
+
+// scanRange reads units matching where and passes them to fn by batches.
+// A scan that failed on a replica after passing batches to fn is not retried
+// on the primary, fn would get the units twice.
+func (u *Units) scanRange(ctx context.Context, run runner, where sq.Sqlizer, fn func(batch models.Units) error) error {
+	var (
+		passed bool
+		failed error
+	)
+	return run(ctx, func(db postgresql.DB) (err error) {
+		if passed && failed != nil {
+			return failed
+		}
+		defer func() {
+			failed = err
+		}()
+
+		rows, err := db.QueryxCtx(ctx, selectUnitBuilder.Where(where))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		batch := make(models.Units, 0, u.scanBatchSize)
+		for rows.Next() {
+			unit := &models.Unit{}
+			if err := scanUnit(rows, unit); err != nil {
+				return err
+			}
+			batch = append(batch, unit)
+			if len(batch) == u.scanBatchSize {
+				passed = true
+				if err := fn(batch); err != nil {
+					return err
+				}
+				batch = make(models.Units, 0, u.scanBatchSize)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			passed = true
+			return fn(batch)
+		}
+		return nil
+	})
+}
+
+// FetchChanges returns units written and tombstones of units deleted at or
+// after since. Watermark of the result is the latest change, or since if
+// nothing has changed.
+func (u *Units) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	const op = "units.Units.FetchChanges"
+
+	changes := &models.Changes{Watermark: since}
+	err := u.run(ctx, func(db postgresql.DB) (err error) {
+		changes.Updated, err = queryUnits(ctx, db, selectUnitBuilder.Where(sq.GtOrEq{"updated_at": since}))
+		if err != nil {
+			return err
+		}
+		changes.Deleted, err = queryDeleted(ctx, db, since, &changes.Watermark)
+		return err
+	})
+	if err != nil {
+		return nil, wrap(op, err)
+	}
+
+	if updatedAt := changes.Updated.LastUpdatedAt(); updatedAt.After(changes.Watermark) {
+		changes.Watermark = updatedAt
+	}
+
+	return changes, nil
+}
+
+// PurgeTombstones removes tombstones of units deleted before the time and
+// returns the number of removed tombstones. Delta syncs that started before
+// the time don't see deletions anymore and must be followed by a full sync.
+func (u *Units) PurgeTombstones(ctx context.Context, before time.Time) (int64, error) {
+	const op = "units.Units.PurgeTombstones"
+
+	var tag pgconn.CommandTag
+	err := u.run(ctx, func(db postgresql.DB) (err error) {
+		tag, err = db.ExecCtx(ctx, `delete from units_deleted where deleted_at < $1`, before)
+		return err
+	})
+	if err != nil {
+		return 0, wrap(op, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func selectByIDs(ids []string) sq.SelectBuilder {
+	return selectUnitBuilder.Where("id = any(?::text[])", ids)
+}
+
+// chunk splits ids into chunks of at most size ids.
+func chunk(ids []string, size int) [][]string {
+	chunks := make([][]string, 0, (len(ids)+size-1)/size)
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size:size])
+		ids = ids[size:]
+	}
+	return append(chunks, ids)
+}
+
+// firstError returns the first error that is not caused
+// by the cancellation after another error.
+func firstError(errs []error) error {
+	var first error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// queryUnits reads units that are expected to number want, fewer units read
+// from a lagging replica are read again from the primary: the missing ones
+// might be created within the lag and must not be cached as not found.
+func (u *Units) queryUnits(ctx context.Context, builder sq.SelectBuilder, want int) (units models.Units, err error) {
+	err = postgresql.RunOnReplicaOrPrimary(ctx, u.db, func(db postgresql.DB) error {
+		units, err = queryUnits(ctx, db, builder)
+		return err
+	}, func() bool {
+		return len(units) < want
+	})
+	return units, err
+}
+
+type runner func(ctx context.Context, fn func(db postgresql.DB) error) error
+
+// run executes fn with statement_timeout limited by the ctx deadline.
+func (u *Units) run(ctx context.Context, fn func(db postgresql.DB) error) error {
+	return postgresql.RunWithStatementTimeout(ctx, u.db, fn)
+}
+
+// runScan is run for full scans, they are limited only by the ctx deadline.
+func (u *Units) runScan(ctx context.Context, fn func(db postgresql.DB) error) error {
+	return postgresql.RunScan(ctx, u.db, fn)
+}
+
+// readScan is runScan for scans that tolerate replication lag like read.
+func (u *Units) readScan(ctx context.Context, fn func(db postgresql.DB) error) error {
+	return postgresql.RunScanOnReplica(ctx, u.db, fn)
+}
+
+// read is run for reads that tolerate replication lag, they are sent
+// to replicas when db has them. FetchChanges reads from the primary, so the
+// watermark never skips changes that replicas haven't replayed yet, and so
+// does Scan.
+func (u *Units) read(ctx context.Context, fn func(db postgresql.DB) error) error {
+	return postgresql.RunOnReplica(ctx, u.db, fn)
+}
+
+func queryUnits(ctx context.Context, db postgresql.DB, builder sq.SelectBuilder) (models.Units, error) {
 	units := make(models.Units, 0)
-	rows, err := u.db.QueryxCtx(ctx, builder)
+	rows, err := db.QueryxCtx(ctx, builder)
 	if err != nil {
 		return nil, err
 	}
@@ -126,14 +537,41 @@ func (u *Units) queryUnits(ctx context.Context, builder sq.SelectBuilder) (model
 		units = append(units, u)
 	}
 
-	return units, nil
+	return units, rows.Err()
+}
+
+// queryDeleted returns ids of units deleted at or after since,
+// watermark is moved to the latest deletion.
+func queryDeleted(ctx context.Context, db postgresql.DB, since time.Time, watermark *time.Time) ([]string, error) {
+	ids := make([]string, 0)
+	rows, err := db.QueryCtx(ctx, `select id, deleted_at from units_deleted where deleted_at >= $1`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id        string
+			deletedAt time.Time
+		)
+		if err := rows.Scan(&id, &deletedAt); err != nil {
+			return nil, err
+		}
+		if deletedAt.After(*watermark) {
+			*watermark = deletedAt
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
 }
 
 func wrap(op string, err error) error {
 	if err == nil {
 		return nil
 	}
-	return fmt.Errorf("%s, %w", op, err)
+	return fmt.Errorf("%s, %w", op, classify(err))
 }
 
 func scanUnit(row pgx.Row, unit *models.Unit) error {
@@ -141,5 +579,6 @@ func scanUnit(row pgx.Row, unit *models.Unit) error {
 		&unit.ID,
 		&unit.Data,
 		&unit.CreatedAt,
+		&unit.UpdatedAt,
 	)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/AltMax/art-test/config"
 	"github.com/AltMax/art-test/models"
 	"github.com/AltMax/art-test/postgresql"
+	"github.com/AltMax/art-test/units"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/require"
@@ -28,7 +29,7 @@ func Test_Create_Positive(t *testing.T) {
 	unit := randomUnit()
 
 	actualUnit := &models.Unit{}
-	row := postgresDB.QueryRowCtx(ctx, `select id, data, created_at from units where id = $1`, unit.ID)
+	row := postgresDB.QueryRowCtx(ctx, `select id, data, created_at, updated_at from units where id = $1`, unit.ID)
 	err = scanUnit(row, actualUnit)
 	require.ErrorIs(t, err, pgx.ErrNoRows)
 
@@ -36,7 +37,7 @@ func Test_Create_Positive(t *testing.T) {
 	require.NoError(t, err)
 
 	actualUnit = &models.Unit{}
-	row = postgresDB.QueryRowCtx(ctx, `select id, data, created_at from units where id = $1`, unit.ID)
+	row = postgresDB.QueryRowCtx(ctx, `select id, data, created_at, updated_at from units where id = $1`, unit.ID)
 	err = scanUnit(row, actualUnit)
 	require.NoError(t, err)
 	require.Equal(t, unit, actualUnit)
@@ -59,7 +60,7 @@ func Test_Create_Duplicate(t *testing.T) {
 	require.NoError(t, err)
 
 	actualUnit := &models.Unit{}
-	row := postgresDB.QueryRowCtx(ctx, `select id, data, created_at from units where id = $1`, unit.ID)
+	row := postgresDB.QueryRowCtx(ctx, `select id, data, created_at, updated_at from units where id = $1`, unit.ID)
 	err = scanUnit(row, actualUnit)
 	require.NoError(t, err)
 	require.Equal(t, unit, actualUnit)
@@ -71,7 +72,7 @@ func Test_Create_Duplicate(t *testing.T) {
 	require.NoError(t, err)
 
 	actualUnit = &models.Unit{}
-	row = postgresDB.QueryRowCtx(ctx, `select id, data, created_at from units where id = $1`, unit.ID)
+	row = postgresDB.QueryRowCtx(ctx, `select id, data, created_at, updated_at from units where id = $1`, unit.ID)
 	err = scanUnit(row, actualUnit)
 	require.NoError(t, err)
 	require.Equal(t, unit, actualUnit)
@@ -94,9 +95,12 @@ func Test_Update_Positive(t *testing.T) {
 	require.NoError(t, err)
 
 	unit.Data = []byte("updated data")
+	createdAt := unit.UpdatedAt
 
 	updatedUnit, err := testUnits.Update(ctx, unit.ID, unit.Data)
 	require.NoError(t, err)
+	require.True(t, updatedUnit.UpdatedAt.After(createdAt))
+	unit.UpdatedAt = updatedUnit.UpdatedAt
 	require.Equal(t, unit, updatedUnit)
 
 	actualUnit, err := testUnits.FindByID(ctx, unit.ID)
@@ -131,6 +135,44 @@ func Test_Update_NotFound(t *testing.T) {
 	require.Equal(t, unit, actualUnit)
 }
 
+func Test_WriteBatch(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	testUnits := NewUnits(postgresDB)
+	ctx := context.Background()
+
+	existing, created, missing := randomUnit(), randomUnit(), randomUnit()
+	err = testUnits.Create(ctx, existing)
+	require.NoError(t, err)
+
+	updated := *existing
+	updated.Data = []byte("updated data")
+	recreated := *existing
+	recreated.Data = []byte("recreated data")
+	notFound, existed, err := testUnits.WriteBatch(ctx, models.Units{created, &recreated}, models.Units{&updated, missing})
+	require.NoError(t, err)
+	require.Equal(t, []string{missing.ID}, notFound)
+	require.Equal(t, []string{existing.ID}, existed)
+
+	actualUnit, err := testUnits.FindByID(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, updated.Data, actualUnit.Data)
+	require.Equal(t, actualUnit.UpdatedAt, updated.UpdatedAt)
+
+	actualUnit, err = testUnits.FindByID(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created.Data, actualUnit.Data)
+	require.Equal(t, actualUnit.UpdatedAt, created.UpdatedAt)
+
+	_, err = testUnits.FindByID(ctx, missing.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
 func Test_Delete_Positive(t *testing.T) {
 	conf, err := config.New()
 	require.NoError(t, err)
@@ -203,6 +245,42 @@ func Test_FindByIDs_Positive(t *testing.T) {
 	require.Equal(t, expectedUnits, actualUnits)
 }
 
+func Test_FindByIDs_Chunks(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	testUnits := NewUnits(postgresDB, WithFindChunks(3, 2))
+	ctx := context.Background()
+
+	expectedUnits := models.Units{}
+	ids := []string{}
+	for i := 0; i < 10; i++ {
+		unit := randomUnit()
+		err := testUnits.Create(ctx, unit)
+		require.NoError(t, err)
+		expectedUnits = append(expectedUnits, unit)
+		ids = append(ids, unit.ID, uuid.NewString())
+	}
+
+	actualUnits, err := testUnits.FindByIDs(ctx, ids)
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedUnits, actualUnits)
+}
+
+func Test_chunk(t *testing.T) {
+	ids := []string{"1", "2", "3", "4", "5"}
+	require.Equal(t, [][]string{{"1", "2"}, {"3", "4"}, {"5"}}, chunk(ids, 2))
+	require.Equal(t, [][]string{{"1", "2", "3", "4", "5"}}, chunk(ids, 5))
+
+	chunks := chunk(ids, 2)
+	chunks[0] = append(chunks[0], "6")
+	require.Equal(t, "3", ids[2])
+}
+
 func Test_FetchAll(t *testing.T) {
 	conf, err := config.New()
 	require.NoError(t, err)
@@ -239,3 +317,147 @@ func randomUnit() *models.Unit {
 		CreatedAt: time.Now().UTC(),
 	}
 }
+
+func Test_Scan_Partitions(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	testUnits := NewUnits(postgresDB, WithScan(3, 4))
+	ctx := context.Background()
+
+	_, err = postgresDB.ExecCtx(ctx, `delete from units`)
+	require.NoError(t, err)
+
+	units := models.Units{}
+	for i := 0; i < 20; i++ {
+		unit := randomUnit()
+		err := testUnits.Create(ctx, unit)
+		require.NoError(t, err)
+		units = append(units, unit)
+	}
+
+	scanned := models.Units{}
+	err = testUnits.Scan(ctx, func(batch models.Units) error {
+		require.LessOrEqual(t, len(batch), 3)
+		scanned = append(scanned, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, units, scanned)
+}
+
+func Test_StatementTimeout(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = postgresql.RunWithStatementTimeout(ctx, postgresDB, func(db postgresql.DB) error {
+		_, err := db.ExecCtx(context.Background(), `select pg_sleep(1)`)
+		return err
+	})
+	require.ErrorIs(t, classify(err), units.ErrDeadlineExceeded)
+}
+
+func Test_RunScan(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+	conf.Postgresql.StatementTimeout = time.Second
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	var timeout string
+	err = postgresql.RunScan(context.Background(), postgresDB, func(db postgresql.DB) error {
+		return db.QueryRowCtx(context.Background(), `show statement_timeout`).Scan(&timeout)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0", timeout)
+}
+
+func Test_PurgeTombstones(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	testUnits := NewUnits(postgresDB)
+	ctx := context.Background()
+
+	deleted := randomUnit()
+	err = testUnits.Create(ctx, deleted)
+	require.NoError(t, err)
+	err = testUnits.Delete(ctx, deleted.ID)
+	require.NoError(t, err)
+
+	changes, err := testUnits.FetchChanges(ctx, deleted.UpdatedAt)
+	require.NoError(t, err)
+	require.Contains(t, changes.Deleted, deleted.ID)
+
+	purged, err := testUnits.PurgeTombstones(ctx, changes.Watermark.Add(time.Microsecond))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, int64(1))
+
+	changes, err = testUnits.FetchChanges(ctx, deleted.UpdatedAt)
+	require.NoError(t, err)
+	require.NotContains(t, changes.Deleted, deleted.ID)
+}
+
+func Test_FetchChanges(t *testing.T) {
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	postgresDB, err := postgresql.NewConnectionPool(conf.Postgresql)
+	require.NoError(t, err)
+	defer postgresDB.Close()
+
+	testUnits := NewUnits(postgresDB)
+	ctx := context.Background()
+
+	unchanged := randomUnit()
+	err = testUnits.Create(ctx, unchanged)
+	require.NoError(t, err)
+
+	deleted := randomUnit()
+	err = testUnits.Create(ctx, deleted)
+	require.NoError(t, err)
+
+	since := deleted.UpdatedAt.Add(time.Microsecond)
+
+	unit := randomUnit()
+	err = testUnits.Create(ctx, unit)
+	require.NoError(t, err)
+	err = testUnits.Delete(ctx, deleted.ID)
+	require.NoError(t, err)
+
+	changes, err := testUnits.FetchChanges(ctx, since)
+	require.NoError(t, err)
+	require.Equal(t, models.Units{unit}, changes.Updated)
+	require.Equal(t, []string{deleted.ID}, changes.Deleted)
+	require.True(t, changes.Watermark.After(unit.UpdatedAt))
+
+	changes, err = testUnits.FetchChanges(ctx, changes.Watermark.Add(time.Microsecond))
+	require.NoError(t, err)
+	require.Empty(t, changes.Updated)
+	require.Empty(t, changes.Deleted)
+
+	err = testUnits.Create(ctx, deleted)
+	require.NoError(t, err)
+
+	changes, err = testUnits.FetchChanges(ctx, since)
+	require.NoError(t, err)
+	require.Len(t, changes.Updated, 2)
+	require.Empty(t, changes.Deleted)
+}
@@ -0,0 +1,40 @@
+package units
+
+import "errors"
+
+// Domain errors returned by Units implementations. Storage specific errors are
+// wrapped with one of them (see WithKind) so that transport layers can react to
+// the kind of failure without knowing about the underlying database.
+// Creating a unit that exists is not an error, the unit is kept as is.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrUnavailable        = errors.New("unavailable")
+	ErrDeadlineExceeded   = errors.New("deadline exceeded")
+)
+
+type kindError struct {
+	kind  error
+	cause error
+}
+
+// WithKind marks cause with one of the domain errors. Both the kind and the
+// cause stay reachable with errors.Is and errors.As.
+func WithKind(kind, cause error) error {
+	if cause == nil {
+		return nil
+	}
+	return &kindError{kind: kind, cause: cause}
+}
+
+func (e *kindError) Error() string {
+	return e.kind.Error() + ": " + e.cause.Error()
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *kindError) Unwrap() error {
+	return e.cause
+}
@@ -0,0 +1,153 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+)
+
+// Methods of units.Units recorded by Instrumented.
+const (
+	Create       = "Create"
+	Update       = "Update"
+	Delete       = "Delete"
+	FindByID     = "FindByID"
+	FindByIDs    = "FindByIDs"
+	FetchAll     = "FetchAll"
+	FetchChanges = "FetchChanges"
+	WriteBatch   = "WriteBatch"
+	Scan         = "Scan"
+)
+
+var methods = []string{Create, Update, Delete, FindByID, FindByIDs, FetchAll, FetchChanges, WriteBatch, Scan}
+
+// Instrumented records calls of a layer: their number, errors and latency.
+// Calls of a layer that are not made by the layer over it are the calls
+// answered by the layer over it, e.g. FindByID calls of the store are
+// misses of the cache over it.
+type Instrumented struct {
+	units.Units
+	name    string
+	methods map[string]*methodStats
+	now     func() time.Time
+}
+
+func NewInstrumented(name string, layer units.Units) *Instrumented {
+	i := &Instrumented{
+		Units:   layer,
+		name:    name,
+		methods: make(map[string]*methodStats, len(methods)),
+		now:     time.Now,
+	}
+	for _, method := range methods {
+		i.methods[method] = &methodStats{}
+	}
+	return i
+}
+
+// Unwrap returns the instrumented layer.
+func (i *Instrumented) Unwrap() units.Units {
+	return i.Units
+}
+
+func (i *Instrumented) Create(ctx context.Context, unit *models.Unit) error {
+	start := i.now()
+	err := i.Units.Create(ctx, unit)
+	i.record(Create, start, err, 0, 0)
+	return err
+}
+
+func (i *Instrumented) Update(ctx context.Context, id string, data []byte) (*models.Unit, error) {
+	start := i.now()
+	unit, err := i.Units.Update(ctx, id, data)
+	i.record(Update, start, err, 0, 0)
+	return unit, err
+}
+
+func (i *Instrumented) Delete(ctx context.Context, id string) error {
+	start := i.now()
+	err := i.Units.Delete(ctx, id)
+	i.record(Delete, start, err, 0, 0)
+	return err
+}
+
+func (i *Instrumented) FindByID(ctx context.Context, id string) (*models.Unit, error) {
+	start := i.now()
+	unit, err := i.Units.FindByID(ctx, id)
+	i.record(FindByID, start, err, 0, 0)
+	return unit, err
+}
+
+func (i *Instrumented) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
+	start := i.now()
+	found, err := i.Units.FindByIDs(ctx, ids)
+	i.record(FindByIDs, start, err, len(ids), len(found))
+	return found, err
+}
+
+func (i *Instrumented) FetchAll(ctx context.Context) (models.Units, error) {
+	start := i.now()
+	fetched, err := i.Units.FetchAll(ctx)
+	i.record(FetchAll, start, err, 0, len(fetched))
+	return fetched, err
+}
+
+func (i *Instrumented) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	start := i.now()
+	changes, err := i.Units.FetchChanges(ctx, since)
+	returned := 0
+	if changes != nil {
+		returned = len(changes.Updated) + len(changes.Deleted)
+	}
+	i.record(FetchChanges, start, err, 0, returned)
+	return changes, err
+}
+
+// WriteBatch passes batch writes through, so layers over it that
+// write in batches keep working.
+func (i *Instrumented) WriteBatch(ctx context.Context, created, updated models.Units) ([]string, []string, error) {
+	start := i.now()
+	notFound, existing, err := units.WriteBatch(ctx, i.Units, created, updated)
+	i.record(WriteBatch, start, err, len(created)+len(updated), len(created)+len(updated)-len(notFound)-len(existing))
+	return notFound, existing, err
+}
+
+// Scan passes scans through, its latency includes the time spent in fn.
+func (i *Instrumented) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	start := i.now()
+	returned := 0
+	err := units.Scan(ctx, i.Units, func(batch models.Units) error {
+		returned += len(batch)
+		return fn(batch)
+	})
+	i.record(Scan, start, err, 0, returned)
+	return err
+}
+
+// record counts a call, ErrNotFound is counted apart from other errors
+// since it's a regular answer of a lookup.
+func (i *Instrumented) record(method string, start time.Time, err error, requested, returned int) {
+	stats := i.methods[method]
+	switch {
+	case err == nil:
+		stats.record(i.now().Sub(start), requested, returned)
+	case errors.Is(err, units.ErrNotFound):
+		stats.recordNotFound(i.now().Sub(start))
+	default:
+		stats.recordError(i.now().Sub(start))
+	}
+}
+
+// Stats returns stats of methods that were called.
+func (i *Instrumented) Stats() LayerStats {
+	stats := LayerStats{Layer: i.name}
+	for _, method := range methods {
+		if s := i.methods[method].stats(method); s.Calls > 0 {
+			stats.Methods = append(stats.Methods, s)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,82 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Instrumented(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	registry := NewRegistry()
+	layer := registry.Wrap("store", unitsMock).(*Instrumented)
+
+	// every call takes 1.5ms
+	now := time.Now()
+	layer.now = func() time.Time {
+		now = now.Add(time.Millisecond + 500*time.Microsecond)
+		return now
+	}
+
+	ctx := context.Background()
+	unit := &models.Unit{ID: "1"}
+	unitsMock.On("FindByID", mock.Anything, "1").Return(unit, nil)
+	unitsMock.On("FindByID", mock.Anything, "2").Return(nil, units.ErrNotFound)
+	unitsMock.On("FindByID", mock.Anything, "3").Return(nil, errors.New("connection refused"))
+	unitsMock.On("FindByIDs", mock.Anything, []string{"1", "2"}).Return(models.Units{unit}, nil)
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, _ = layer.FindByID(ctx, id)
+	}
+	found, err := layer.FindByIDs(ctx, []string{"1", "2"})
+	require.NoError(t, err)
+	require.Equal(t, models.Units{unit}, found)
+
+	stats := registry.Stats()
+	require.Len(t, stats, 1)
+	require.Equal(t, "store", stats[0].Layer)
+	require.Equal(t, []MethodStats{
+		{
+			Method:   FindByID,
+			Calls:    3,
+			Errors:   1,
+			NotFound: 1,
+			Total:    4500 * time.Microsecond,
+			P50:      2500 * time.Microsecond,
+			P99:      2500 * time.Microsecond,
+		},
+		{
+			Method:    FindByIDs,
+			Calls:     1,
+			Requested: 2,
+			Returned:  1,
+			Total:     1500 * time.Microsecond,
+			P50:       2500 * time.Microsecond,
+			P99:       2500 * time.Microsecond,
+		},
+	}, stats[0].Methods)
+}
+
+func Test_quantile(t *testing.T) {
+	buckets := make([]uint64, len(latencyBuckets)+1)
+	buckets[0] = 90
+	buckets[4] = 9
+	buckets[len(latencyBuckets)] = 1
+
+	require.Equal(t, 50*time.Microsecond, quantile(buckets, 100, 0.5))
+	require.Equal(t, time.Millisecond, quantile(buckets, 100, 0.99))
+	require.Equal(t, 10*time.Second, quantile(buckets, 100, 1))
+	require.Equal(t, time.Duration(0), quantile(buckets, 0, 0.5))
+}
+
+func Test_Registry_Nil(t *testing.T) {
+	var registry *Registry
+	require.Nil(t, registry.Stats())
+}
@@ -0,0 +1,41 @@
+package instrument
+
+import (
+	"sync"
+
+	"github.com/AltMax/art-test/units"
+)
+
+// Registry keeps instrumented layers of a chain.
+type Registry struct {
+	mu     sync.Mutex
+	layers []*Instrumented
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Wrap instruments layer and registers it under name.
+func (r *Registry) Wrap(name string, layer units.Units) units.Units {
+	instrumented := NewInstrumented(name, layer)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.layers = append(r.layers, instrumented)
+	return instrumented
+}
+
+// Stats returns stats of layers in the order they were registered,
+// a nil registry has no stats.
+func (r *Registry) Stats() []LayerStats {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := make([]LayerStats, 0, len(r.layers))
+	for _, layer := range r.layers {
+		stats = append(stats, layer.Stats())
+	}
+	return stats
+}
@@ -0,0 +1,132 @@
+package instrument
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are upper bounds of latency histogram buckets,
+// the last bucket holds everything slower.
+var latencyBuckets = []time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+type methodStats struct {
+	calls     uint64
+	errors    uint64
+	notFound  uint64
+	requested uint64
+	returned  uint64
+	// total latency in nanoseconds
+	total   uint64
+	buckets [17]uint64 // len(latencyBuckets) + 1
+}
+
+func (s *methodStats) record(latency time.Duration, requested, returned int) {
+	atomic.AddUint64(&s.requested, uint64(requested))
+	atomic.AddUint64(&s.returned, uint64(returned))
+	s.observe(latency)
+}
+
+func (s *methodStats) recordNotFound(latency time.Duration) {
+	atomic.AddUint64(&s.notFound, 1)
+	s.observe(latency)
+}
+
+func (s *methodStats) recordError(latency time.Duration) {
+	atomic.AddUint64(&s.errors, 1)
+	s.observe(latency)
+}
+
+func (s *methodStats) observe(latency time.Duration) {
+	atomic.AddUint64(&s.calls, 1)
+	atomic.AddUint64(&s.total, uint64(latency))
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddUint64(&s.buckets[bucket], 1)
+}
+
+func (s *methodStats) stats(method string) MethodStats {
+	stats := MethodStats{
+		Method:    method,
+		Calls:     atomic.LoadUint64(&s.calls),
+		Errors:    atomic.LoadUint64(&s.errors),
+		NotFound:  atomic.LoadUint64(&s.notFound),
+		Requested: atomic.LoadUint64(&s.requested),
+		Returned:  atomic.LoadUint64(&s.returned),
+		Total:     time.Duration(atomic.LoadUint64(&s.total)),
+	}
+	buckets := make([]uint64, len(s.buckets))
+	var count uint64
+	for i := range s.buckets {
+		buckets[i] = atomic.LoadUint64(&s.buckets[i])
+		count += buckets[i]
+	}
+	stats.P50 = quantile(buckets, count, 0.5)
+	stats.P99 = quantile(buckets, count, 0.99)
+	return stats
+}
+
+// quantile returns the upper bound of the bucket the quantile falls into,
+// the slowest bucket reports twice the largest bound.
+func quantile(buckets []uint64, count uint64, q float64) time.Duration {
+	if count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(count))
+	if rank == 0 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i, n := range buckets {
+		cumulative += n
+		if cumulative >= rank {
+			if i < len(latencyBuckets) {
+				return latencyBuckets[i]
+			}
+			break
+		}
+	}
+	return 2 * latencyBuckets[len(latencyBuckets)-1]
+}
+
+// MethodStats are stats of calls of a method of a layer. Requested and
+// Returned count ids asked for and units returned by FindByIDs, units
+// returned by FetchAll, FetchChanges and Scan, and units written by WriteBatch.
+type MethodStats struct {
+	Method    string        `json:"method"`
+	Calls     uint64        `json:"calls"`
+	Errors    uint64        `json:"errors"`
+	NotFound  uint64        `json:"not_found"`
+	Requested uint64        `json:"requested"`
+	Returned  uint64        `json:"returned"`
+	Total     time.Duration `json:"total_ns"`
+	// P50 and P99 are upper bounds of latency histogram buckets
+	P50 time.Duration `json:"p50_ns"`
+	P99 time.Duration `json:"p99_ns"`
+}
+
+type LayerStats struct {
+	Layer   string        `json:"layer"`
+	Methods []MethodStats `json:"methods"`
+}
@@ -7,6 +7,8 @@ import (
 
 	models "github.com/AltMax/art-test/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Units is an autogenerated mock type for the Units type
@@ -65,6 +67,29 @@ func (_m *Units) FetchAll(ctx context.Context) (models.Units, error) {
 	return r0, r1
 }
 
+// FetchChanges provides a mock function with given fields: ctx, since
+func (_m *Units) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	ret := _m.Called(ctx, since)
+
+	var r0 *models.Changes
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) *models.Changes); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Changes)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByID provides a mock function with given fields: ctx, id
 func (_m *Units) FindByID(ctx context.Context, id string) (*models.Unit, error) {
 	ret := _m.Called(ctx, id)
@@ -0,0 +1,171 @@
+package units
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// NegativeCache remembers ids that were not found for a while, so repeated
+// lookups of missing units don't reach the next layer. A nil *NegativeCache
+// remembers nothing.
+//
+// Lookups take the generation before reading the next layer and add ids
+// with AddSince, so an id removed by a create made while the lookup was in
+// flight is not remembered as missing.
+type NegativeCache struct {
+	ttl time.Duration
+	ids *lru.Cache[string, time.Time]
+	now func() time.Time
+
+	// mu orders adds of lookups with removals
+	mu  sync.Mutex
+	gen uint64
+
+	hits   uint64
+	misses uint64
+}
+
+// NegativeStats describes usage of a NegativeCache.
+type NegativeStats struct {
+	Entries int
+	Hits    uint64
+	Misses  uint64
+}
+
+// NewNegativeCache returns a cache of at most size ids, each is kept for ttl.
+func NewNegativeCache(size int, ttl time.Duration) (*NegativeCache, error) {
+	ids, err := lru.New[string, time.Time](size)
+	if err != nil {
+		return nil, err
+	}
+	return &NegativeCache{ttl: ttl, ids: ids, now: time.Now}, nil
+}
+
+// Add remembers ids as missing.
+func (c *NegativeCache) Add(ids ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(ids...)
+}
+
+// Generation changes every time ids are removed.
+func (c *NegativeCache) Generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// AddSince remembers ids as missing unless ids were removed since
+// the generation gen was taken.
+func (c *NegativeCache) AddSince(gen uint64, ids ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.add(ids...)
+	}
+}
+
+func (c *NegativeCache) add(ids ...string) {
+	expiresAt := c.now().Add(c.ttl)
+	for _, id := range ids {
+		c.ids.Add(id, expiresAt)
+	}
+}
+
+// Contains reports whether id is known to be missing.
+func (c *NegativeCache) Contains(id string) bool {
+	if c == nil {
+		return false
+	}
+	expiresAt, ok := c.ids.Get(id)
+	if ok && c.now().After(expiresAt) {
+		c.ids.Remove(id)
+		ok = false
+	}
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	return ok
+}
+
+// Remove forgets ids, e.g. when units with them are created.
+func (c *NegativeCache) Remove(ids ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, id := range ids {
+		c.ids.Remove(id)
+	}
+}
+
+func (c *NegativeCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.ids.Purge()
+}
+
+func (c *NegativeCache) Stats() NegativeStats {
+	if c == nil {
+		return NegativeStats{}
+	}
+	return NegativeStats{
+		Entries: c.ids.Len(),
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+	}
+}
+
+// unknown filters out ids that are known to be missing.
+func (c *NegativeCache) unknown(ids []string) []string {
+	if c == nil {
+		return ids
+	}
+	unknown := ids[:0]
+	for _, id := range ids {
+		if !c.Contains(id) {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown
+}
+
+// addNotFound remembers ids that are missing from found units,
+// see AddSince.
+func (c *NegativeCache) addNotFound(gen uint64, ids []string, found []*models.Unit) {
+	if c == nil || len(found) == len(ids) {
+		return
+	}
+	foundIDs := make(map[string]struct{}, len(found))
+	for _, unit := range found {
+		foundIDs[unit.ID] = struct{}{}
+	}
+	notFound := make([]string, 0)
+	for _, id := range ids {
+		if _, ok := foundIDs[id]; !ok {
+			notFound = append(notFound, id)
+		}
+	}
+	c.AddSince(gen, notFound...)
+}
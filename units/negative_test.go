@@ -0,0 +1,61 @@
+package units
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NegativeCache_TTL(t *testing.T) {
+	negative, err := NewNegativeCache(10, time.Minute)
+	require.NoError(t, err)
+
+	now := time.Now()
+	negative.now = func() time.Time { return now }
+
+	negative.Add("1")
+	require.True(t, negative.Contains("1"))
+	require.False(t, negative.Contains("2"))
+
+	now = now.Add(2 * time.Minute)
+	require.False(t, negative.Contains("1"))
+	require.Equal(t, NegativeStats{Entries: 0, Hits: 1, Misses: 2}, negative.Stats())
+}
+
+func Test_NegativeCache_Size(t *testing.T) {
+	negative, err := NewNegativeCache(2, time.Minute)
+	require.NoError(t, err)
+
+	negative.Add("1", "2", "3")
+	require.False(t, negative.Contains("1"))
+	require.True(t, negative.Contains("3"))
+
+	negative.Remove("3")
+	require.False(t, negative.Contains("3"))
+}
+
+func Test_NegativeCache_Generation(t *testing.T) {
+	negative, err := NewNegativeCache(10, time.Minute)
+	require.NoError(t, err)
+
+	// the unit is created while it's looked up
+	gen := negative.Generation()
+	negative.Remove("1")
+	negative.AddSince(gen, "1")
+	require.False(t, negative.Contains("1"))
+
+	negative.addNotFound(negative.Generation(), []string{"1", "2"}, []*models.Unit{{ID: "2"}})
+	require.True(t, negative.Contains("1"))
+	require.False(t, negative.Contains("2"))
+}
+
+func Test_NegativeCache_Nil(t *testing.T) {
+	var negative *NegativeCache
+	negative.Add("1")
+	require.False(t, negative.Contains("1"))
+	require.Equal(t, []string{"1"}, negative.unknown([]string{"1"}))
+	negative.addNotFound(negative.Generation(), []string{"1"}, []*models.Unit{})
+	require.Equal(t, NegativeStats{}, negative.Stats())
+}
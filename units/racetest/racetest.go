@@ -0,0 +1,205 @@
+// Package racetest reproduces lost writes of in-memory layers: the next
+// layer returns a unit or reports it missing, the unit is written through
+// the layer and only then the layer saves what it has read. The layer must
+// keep the written unit, forget the deleted or invalidated one and must not
+// remember the created one as missing.
+package racetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Read is a way a layer reads units from the next layer.
+type Read struct {
+	Name string
+	// Cached is set when the layer must keep the unit before the read,
+	// e.g. caches refresh only cached units by syncs.
+	Cached bool
+	// On makes next return stale, nil stale means the unit with the id is missing.
+	On func(next *mocks.Units, id string, stale *models.Unit) *mock.Call
+	// Call makes layer read the unit with the id from next.
+	Call func(ctx context.Context, layer units.Units, id string) error
+}
+
+var Reads = []Read{
+	{
+		Name: "FindByID",
+		On: func(next *mocks.Units, id string, stale *models.Unit) *mock.Call {
+			if stale == nil {
+				return next.On("FindByID", mock.Anything, id).Return(nil, units.ErrNotFound)
+			}
+			return next.On("FindByID", mock.Anything, id).Return(stale, nil)
+		},
+		Call: func(ctx context.Context, layer units.Units, id string) error {
+			_, err := layer.FindByID(ctx, id)
+			return err
+		},
+	},
+	{
+		Name: "FindByIDs",
+		On: func(next *mocks.Units, id string, stale *models.Unit) *mock.Call {
+			return next.On("FindByIDs", mock.Anything, []string{id}).Return(found(stale), nil)
+		},
+		Call: func(ctx context.Context, layer units.Units, id string) error {
+			_, err := layer.FindByIDs(ctx, []string{id})
+			return err
+		},
+	},
+	{
+		Name:   "FetchAll",
+		Cached: true,
+		On: func(next *mocks.Units, id string, stale *models.Unit) *mock.Call {
+			return next.On("FetchAll", mock.Anything).Return(found(stale), nil)
+		},
+		Call: func(ctx context.Context, layer units.Units, id string) error {
+			_, err := layer.FetchAll(ctx)
+			return err
+		},
+	},
+	{
+		Name:   "FetchChanges",
+		Cached: true,
+		On: func(next *mocks.Units, id string, stale *models.Unit) *mock.Call {
+			changes := &models.Changes{Updated: found(stale)}
+			return next.On("FetchChanges", mock.Anything, mock.Anything).Return(changes, nil)
+		},
+		Call: func(ctx context.Context, layer units.Units, id string) error {
+			_, err := layer.FetchChanges(ctx, time.Time{})
+			return err
+		},
+	},
+}
+
+func found(unit *models.Unit) models.Units {
+	if unit == nil {
+		return models.Units{}
+	}
+	return models.Units{unit}
+}
+
+// Write is a write made through the layer while a read is in flight.
+type Write struct {
+	Name string
+	// Existing is set when the unit exists before the write,
+	// reads return it then and report it missing otherwise.
+	Existing bool
+	// Call makes layer write fresh, next accepts the write.
+	Call func(ctx context.Context, next *mocks.Units, layer units.Units, fresh *models.Unit) error
+	// Deleted is set when the layer must not keep the unit after the write.
+	Deleted bool
+}
+
+var Writes = []Write{
+	{
+		Name:     "Update",
+		Existing: true,
+		Call: func(ctx context.Context, next *mocks.Units, layer units.Units, fresh *models.Unit) error {
+			next.On("Update", mock.Anything, fresh.ID, fresh.Data).Return(fresh, nil).Once()
+			_, err := layer.Update(ctx, fresh.ID, fresh.Data)
+			return err
+		},
+	},
+	{
+		Name: "Create",
+		Call: func(ctx context.Context, next *mocks.Units, layer units.Units, fresh *models.Unit) error {
+			next.On("Create", mock.Anything, fresh).Return(nil).Once()
+			return layer.Create(ctx, fresh)
+		},
+	},
+	{
+		Name:     "Delete",
+		Existing: true,
+		Deleted:  true,
+		Call: func(ctx context.Context, next *mocks.Units, layer units.Units, fresh *models.Unit) error {
+			next.On("Delete", mock.Anything, fresh.ID).Return(nil).Once()
+			return layer.Delete(ctx, fresh.ID)
+		},
+	},
+	{
+		Name:     "InvalidateAll",
+		Existing: true,
+		Deleted:  true,
+		Call: func(ctx context.Context, next *mocks.Units, layer units.Units, fresh *models.Unit) error {
+			layer.(units.Layer).InvalidateAll()
+			return nil
+		},
+	},
+}
+
+// Run runs every read concurrently with every write in the order that loses
+// the write, newLayer creates the layer over next and get returns the unit
+// kept by the layer, nil when there is none. Layers that are
+// units.NegativeCachedLayer must not remember created units as missing.
+func Run(
+	t *testing.T,
+	newLayer func(next *mocks.Units) units.Units,
+	get func(layer units.Units, id string) *models.Unit,
+) {
+	for _, write := range Writes {
+		for _, read := range Reads {
+			t.Run(write.Name+"/"+read.Name, func(t *testing.T) {
+				run(t, write, read, newLayer, get)
+			})
+		}
+	}
+}
+
+func run(
+	t *testing.T,
+	write Write,
+	read Read,
+	newLayer func(next *mocks.Units) units.Units,
+	get func(layer units.Units, id string) *models.Unit,
+) {
+	next := &mocks.Units{}
+	layer := newLayer(next)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	stale := &models.Unit{ID: "1", Data: []byte("stale"), CreatedAt: now, UpdatedAt: now}
+	fresh := &models.Unit{ID: "1", Data: []byte("fresh"), CreatedAt: now, UpdatedAt: now.Add(time.Millisecond)}
+
+	if !write.Existing {
+		stale = nil
+	} else if read.Cached {
+		next.On("Update", mock.Anything, stale.ID, stale.Data).Return(stale, nil).Once()
+		_, err := layer.Update(ctx, stale.ID, stale.Data)
+		require.NoError(t, err)
+	}
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	read.On(next, fresh.ID, stale).Run(func(mock.Arguments) {
+		close(entered)
+		<-release
+	}).Once()
+	done := make(chan error)
+	go func() {
+		done <- read.Call(ctx, layer, fresh.ID)
+	}()
+
+	// the unit is written after the read got it from the next layer
+	<-entered
+	require.NoError(t, write.Call(ctx, next, layer, fresh))
+
+	close(release)
+	if err := <-done; !errors.Is(err, units.ErrNotFound) {
+		require.NoError(t, err)
+	}
+	if write.Deleted {
+		require.Nil(t, get(layer, fresh.ID))
+	} else {
+		require.Equal(t, fresh, get(layer, fresh.ID))
+	}
+	if negative, ok := layer.(units.NegativeCachedLayer); ok && !write.Deleted {
+		require.Zero(t, negative.NegativeStats().Entries)
+	}
+}
@@ -0,0 +1,114 @@
+package units
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultMaxRemovedRatio is the default share by which a full fetch may
+// shrink compared with the previous one before removal is suspended.
+const DefaultMaxRemovedRatio = 0.5
+
+// DefaultRemovalConfirmations is the default number of consecutive agreeing
+// suspicious fetches after which their removal is applied.
+const DefaultRemovalConfirmations = 3
+
+// Reconciliation is the result of reconciling a layer with a full fetch.
+type Reconciliation struct {
+	Added   int
+	Updated int
+	Removed int
+	// Skipped is set when removal was skipped because
+	// the fetch result looked suspiciously small.
+	Skipped bool
+}
+
+// Vanished returns ids that were kept by a layer before a full fetch
+// but are missing from ids of fetched units.
+func Vanished(before []string, fetchedIDs map[string]struct{}) (vanished []string) {
+	for _, id := range before {
+		if _, ok := fetchedIDs[id]; !ok {
+			vanished = append(vanished, id)
+		}
+	}
+	return vanished
+}
+
+// RemovalGuard decides whether units missing from a full fetch are removed.
+// A fetch that has shrunk by more than maxRemovedRatio compared with the last
+// accepted fetch is suspicious, e.g. the database returned an empty result by
+// mistake. Removal is skipped for it, unless confirmations consecutive
+// suspicious fetches agree on the total, then the total is accepted.
+type RemovalGuard struct {
+	mu              sync.Mutex
+	maxRemovedRatio float64
+	confirmations   int
+	// accepted is the total of the last accepted fetch, -1 before the first one.
+	accepted   int
+	suspicious int
+	suspected  int
+}
+
+func NewRemovalGuard(maxRemovedRatio float64, confirmations int) *RemovalGuard {
+	return &RemovalGuard{
+		maxRemovedRatio: maxRemovedRatio,
+		confirmations:   confirmations,
+		accepted:        -1,
+	}
+}
+
+// Seed sets the total the next fetch is compared with, when there's none yet,
+// e.g. the number of units restored from a snapshot.
+func (g *RemovalGuard) Seed(total int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.accepted < 0 {
+		g.accepted = total
+	}
+}
+
+// Accept reports whether units missing from a full fetch of total units
+// are removed.
+func (g *RemovalGuard) Accept(total int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.accepted < 0 || !g.shrunk(g.accepted, total) {
+		g.accepted, g.suspicious = total, 0
+		return true
+	}
+
+	if g.suspicious > 0 && !g.shrunk(g.suspected, total) && !g.shrunk(total, g.suspected) {
+		g.suspicious++
+	} else {
+		g.suspicious = 1
+	}
+	g.suspected = total
+	if g.suspicious >= g.confirmations {
+		g.accepted, g.suspicious = total, 0
+		return true
+	}
+	return false
+}
+
+// shrunk reports whether total is less than from by more than maxRemovedRatio.
+func (g *RemovalGuard) shrunk(from, total int) bool {
+	return float64(total) < (1-g.maxRemovedRatio)*float64(from)
+}
+
+func (r Reconciliation) MarshalZerologObject(e *zerolog.Event) {
+	e.Int("added", r.Added).Int("updated", r.Updated).Int("removed", r.Removed).Bool("skipped", r.Skipped)
+}
+
+// LogReconciliation logs r with the logger of ctx,
+// skipped removal is logged as a warning.
+func LogReconciliation(ctx context.Context, layer string, r Reconciliation) {
+	logger := zerolog.Ctx(ctx)
+	event := logger.Info()
+	if r.Skipped {
+		event = logger.Warn()
+	}
+	event.Str("layer", layer).Object("reconciliation", r).Msg("units reconciled")
+}
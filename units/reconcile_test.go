@@ -0,0 +1,47 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RemovalGuard(t *testing.T) {
+	g := NewRemovalGuard(0.5, 3)
+
+	// the first fetch has nothing to compare with
+	require.True(t, g.Accept(100))
+	require.True(t, g.Accept(60))
+
+	// a fetch that has shrunk too much is applied after three agreeing ones
+	require.False(t, g.Accept(0))
+	require.False(t, g.Accept(0))
+	require.True(t, g.Accept(0))
+	require.True(t, g.Accept(0))
+
+	// fetches within the ratio of each other agree
+	require.True(t, g.Accept(10))
+	require.False(t, g.Accept(2))
+	require.False(t, g.Accept(4))
+	require.True(t, g.Accept(3))
+
+	// fetches that don't agree start counting again
+	require.True(t, g.Accept(100))
+	require.False(t, g.Accept(2))
+	require.False(t, g.Accept(40))
+	require.False(t, g.Accept(2))
+	require.False(t, g.Accept(2))
+	require.True(t, g.Accept(2))
+}
+
+func Test_RemovalGuard_Seed(t *testing.T) {
+	g := NewRemovalGuard(0.5, 3)
+	g.Seed(10)
+	require.False(t, g.Accept(0))
+
+	// seed doesn't override fetches
+	g = NewRemovalGuard(0.5, 3)
+	require.True(t, g.Accept(0))
+	g.Seed(10)
+	require.True(t, g.Accept(0))
+}
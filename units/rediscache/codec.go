@@ -0,0 +1,65 @@
+package rediscache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+)
+
+// codecVersion is the first byte of values, values of other versions
+// are treated as missing, so instances of different versions can share redis.
+const codecVersion byte = 1
+
+var errUnknownCodec = errors.New("unknown unit encoding")
+
+// encode stores a unit as version | created at varint | updated at varint | data,
+// times are unix nanoseconds, 0 is zero time. The id is the key.
+func encode(unit *models.Unit) []byte {
+	buf := make([]byte, 1+2*binary.MaxVarintLen64+len(unit.Data))
+	buf[0] = codecVersion
+	n := 1
+	n += binary.PutVarint(buf[n:], unixNano(unit.CreatedAt))
+	n += binary.PutVarint(buf[n:], unixNano(unit.UpdatedAt))
+	n += copy(buf[n:], unit.Data)
+	return buf[:n]
+}
+
+func decode(id string, value []byte) (*models.Unit, error) {
+	if len(value) == 0 || value[0] != codecVersion {
+		return nil, errUnknownCodec
+	}
+	n := 1
+	createdAt, size := binary.Varint(value[n:])
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: bad created at", errUnknownCodec)
+	}
+	n += size
+	updatedAt, size := binary.Varint(value[n:])
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: bad updated at", errUnknownCodec)
+	}
+	n += size
+	return &models.Unit{
+		ID:        id,
+		Data:      value[n:],
+		CreatedAt: fromUnixNano(createdAt),
+		UpdatedAt: fromUnixNano(updatedAt),
+	}, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
@@ -0,0 +1,245 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPrefix = "units:"
+	defaultTTL    = 10 * time.Minute
+
+	// invalidateTimeout bounds invalidations that are not made by requests.
+	invalidateTimeout = 10 * time.Second
+	scanBatchSize     = 1000
+)
+
+// Cache keeps units in a redis server shared by all instances of the service.
+// Units are removed from redis after every write instead of being updated,
+// so concurrent writes of other instances are not overwritten with older
+// units. A lookup that read a unit before a write may still save it after
+// the write, such units live until the ttl expires.
+// Redis errors are logged and the next layer is used instead.
+type Cache struct {
+	units.Units
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+type Option func(*Cache)
+
+// WithPrefix sets the prefix of keys of units, units:<id> is used by default.
+func WithPrefix(prefix string) Option {
+	return func(c *Cache) {
+		c.prefix = prefix
+	}
+}
+
+// WithTTL sets the time units are kept in redis for, it must be positive:
+// it bounds how long a unit saved by a lookup racing a write stays stale.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+func NewCache(next units.Units, client redis.UniversalClient, opts ...Option) (*Cache, error) {
+	c := &Cache{
+		Units:  next,
+		client: client,
+		prefix: defaultPrefix,
+		ttl:    defaultTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.ttl <= 0 {
+		return nil, errors.New("redis cache ttl must be positive")
+	}
+	return c, nil
+}
+
+func (c *Cache) Create(ctx context.Context, unit *models.Unit) error {
+	err := c.Units.Create(ctx, unit)
+	c.del(ctx, unit.ID)
+	return err
+}
+
+func (c *Cache) Update(ctx context.Context, id string, data []byte) (*models.Unit, error) {
+	unit, err := c.Units.Update(ctx, id, data)
+	c.del(ctx, id)
+	return unit, err
+}
+
+func (c *Cache) Delete(ctx context.Context, id string) error {
+	err := c.Units.Delete(ctx, id)
+	c.del(ctx, id)
+	return err
+}
+
+func (c *Cache) FindByID(ctx context.Context, id string) (*models.Unit, error) {
+	value, err := c.client.Get(ctx, c.key(id)).Bytes()
+	switch {
+	case err == nil:
+		unit, err := decode(id, value)
+		if err == nil {
+			return unit, nil
+		}
+		logger(ctx).Warn().Err(err).Str("id", id).Msg("decode unit from redis")
+	case !errors.Is(err, redis.Nil):
+		logger(ctx).Warn().Err(err).Msg("get unit from redis")
+	}
+
+	unit, err := c.Units.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.set(ctx, unit)
+	return unit, nil
+}
+
+// FindByIDs gets ids from redis with a single pipeline
+// and looks up the missing ones in the next layer.
+func (c *Cache) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
+	return units.FindByIDs(ctx, ids, func(ids []string) []*models.Unit {
+		return c.getByIDs(ctx, ids)
+	}, func(found ...*models.Unit) {
+		c.set(ctx, found...)
+	}, c.Units, nil)
+}
+
+// WriteBatch removes written units from redis like other writes.
+func (c *Cache) WriteBatch(ctx context.Context, created, updated models.Units) ([]string, []string, error) {
+	notFound, existing, err := units.WriteBatch(ctx, c.Units, created, updated)
+	c.del(ctx, append(created.IDs(), updated.IDs()...)...)
+	return notFound, existing, err
+}
+
+// Scan passes scans through, units are not cached in redis by full fetches.
+func (c *Cache) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	return units.Scan(ctx, c.Units, fn)
+}
+
+// FetchChanges removes changed units from redis, so writes made
+// bypassing the service are not served from redis until the ttl expires.
+func (c *Cache) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	changes, err := c.Units.FetchChanges(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	c.del(ctx, append(changes.Updated.IDs(), changes.Deleted...)...)
+	return changes, nil
+}
+
+func (c *Cache) Invalidate(ids ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), invalidateTimeout)
+	defer cancel()
+	c.del(ctx, ids...)
+}
+
+// InvalidateAll removes all keys with the prefix. Keys are scanned on
+// a single server, cluster clients scan only one of its nodes.
+func (c *Cache) InvalidateAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), invalidateTimeout)
+	defer cancel()
+
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", scanBatchSize).Iterator()
+	keys := make([]string, 0, scanBatchSize)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == scanBatchSize {
+			c.delKeys(ctx, keys)
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Warn().Err(err).Msg("scan units in redis")
+	}
+	c.delKeys(ctx, keys)
+}
+
+func (c *Cache) getByIDs(ctx context.Context, ids []string) []*models.Unit {
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.Get(ctx, c.key(id)))
+	}
+	// errors of single commands are checked below, redis.Nil is one of them
+	_, err := pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger(ctx).Warn().Err(err).Msg("get units from redis")
+		return nil
+	}
+
+	found := make([]*models.Unit, 0, len(ids))
+	for i, cmd := range cmds {
+		value, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		unit, err := decode(ids[i], value)
+		if err != nil {
+			logger(ctx).Warn().Err(err).Str("id", ids[i]).Msg("decode unit from redis")
+			continue
+		}
+		found = append(found, unit)
+	}
+	return found
+}
+
+func (c *Cache) set(ctx context.Context, found ...*models.Unit) {
+	if len(found) == 0 {
+		return
+	}
+	pipe := c.client.Pipeline()
+	for _, unit := range found {
+		pipe.Set(ctx, c.key(unit.ID), encode(unit), c.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger(ctx).Warn().Err(err).Msg("set units in redis")
+	}
+}
+
+func (c *Cache) del(ctx context.Context, ids ...string) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, c.key(id))
+	}
+	c.delKeys(ctx, keys)
+}
+
+func (c *Cache) delKeys(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	// keys are deleted one by one, so cluster clients route them to their nodes
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger(ctx).Error().Err(err).Msg("delete units from redis")
+	}
+}
+
+func (c *Cache) key(id string) string {
+	return c.prefix + id
+}
+
+// logger returns the logger of the request, the global logger is used
+// outside of requests.
+func logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx)
+	if l.GetLevel() == zerolog.Disabled {
+		return &log.Logger
+	}
+	return l
+}
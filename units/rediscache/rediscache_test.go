@@ -0,0 +1,156 @@
+package rediscache
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T, opts ...Option) (*Cache, *mocks.Units, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	unitsMock := &mocks.Units{}
+	testCache, err := NewCache(unitsMock, client, opts...)
+	require.NoError(t, err)
+	return testCache, unitsMock, server
+}
+
+func Test_NewCache_TTL(t *testing.T) {
+	_, err := NewCache(&mocks.Units{}, redis.NewClient(&redis.Options{}), WithTTL(0))
+	require.Error(t, err)
+}
+
+func Test_FindByID(t *testing.T) {
+	testCache, unitsMock, server := newTestCache(t, WithPrefix("test:"), WithTTL(time.Minute))
+	ctx := context.Background()
+
+	unit := randomUnit()
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+
+	actualUnit, err := testCache.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, unit, actualUnit)
+	require.True(t, server.Exists("test:"+unit.ID))
+	require.Equal(t, time.Minute, server.TTL("test:"+unit.ID))
+
+	// served from redis
+	actualUnit, err = testCache.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, unit, actualUnit)
+	unitsMock.AssertExpectations(t)
+
+	// expired
+	server.FastForward(time.Minute)
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(nil, units.ErrNotFound).Once()
+	_, err = testCache.FindByID(ctx, unit.ID)
+	require.ErrorIs(t, err, units.ErrNotFound)
+	require.False(t, server.Exists("test:"+unit.ID))
+}
+
+func Test_FindByIDs(t *testing.T) {
+	testCache, unitsMock, _ := newTestCache(t)
+	ctx := context.Background()
+
+	cached, missed := randomUnit(), randomUnit()
+	unitsMock.On("FindByID", mock.Anything, cached.ID).Return(cached, nil).Once()
+	_, err := testCache.FindByID(ctx, cached.ID)
+	require.NoError(t, err)
+
+	unitsMock.On("FindByIDs", mock.Anything, []string{missed.ID, "unknown"}).Return(models.Units{missed}, nil).Once()
+	found, err := testCache.FindByIDs(ctx, []string{cached.ID, missed.ID, "unknown", cached.ID})
+	require.NoError(t, err)
+	require.Equal(t, models.Units{cached, missed}, found)
+
+	// both are served from redis now
+	found, err = testCache.FindByIDs(ctx, []string{missed.ID, cached.ID})
+	require.NoError(t, err)
+	require.ElementsMatch(t, models.Units{cached, missed}, found)
+	unitsMock.AssertExpectations(t)
+}
+
+func Test_Writes_Invalidate(t *testing.T) {
+	testCache, unitsMock, server := newTestCache(t)
+	ctx := context.Background()
+
+	unit := randomUnit()
+	key := defaultPrefix + unit.ID
+	fill := func() {
+		unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+		_, err := testCache.FindByID(ctx, unit.ID)
+		require.NoError(t, err)
+		require.True(t, server.Exists(key))
+	}
+
+	fill()
+	unitsMock.On("Update", mock.Anything, unit.ID, []byte("updated")).Return(unit, nil)
+	_, err := testCache.Update(ctx, unit.ID, []byte("updated"))
+	require.NoError(t, err)
+	require.False(t, server.Exists(key))
+
+	fill()
+	unitsMock.On("Delete", mock.Anything, unit.ID).Return(nil)
+	err = testCache.Delete(ctx, unit.ID)
+	require.NoError(t, err)
+	require.False(t, server.Exists(key))
+
+	fill()
+	unitsMock.On("FetchChanges", mock.Anything, mock.Anything).Return(&models.Changes{Updated: models.Units{unit}}, nil)
+	_, err = testCache.FetchChanges(ctx, time.Now())
+	require.NoError(t, err)
+	require.False(t, server.Exists(key))
+
+	fill()
+	require.NoError(t, server.Set("other", "value"))
+	testCache.InvalidateAll()
+	require.False(t, server.Exists(key))
+	require.True(t, server.Exists("other"))
+}
+
+func Test_RedisUnavailable(t *testing.T) {
+	testCache, unitsMock, server := newTestCache(t)
+	ctx := context.Background()
+
+	unit := randomUnit()
+	require.NoError(t, server.Set(defaultPrefix+unit.ID, "corrupt"))
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+
+	// corrupt values are misses
+	actualUnit, err := testCache.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, unit, actualUnit)
+
+	server.Close()
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+	actualUnit, err = testCache.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, unit, actualUnit)
+
+	unitsMock.On("FindByIDs", mock.Anything, []string{unit.ID}).Return(models.Units{unit}, nil).Once()
+	found, err := testCache.FindByIDs(ctx, []string{unit.ID})
+	require.NoError(t, err)
+	require.Equal(t, models.Units{unit}, found)
+	unitsMock.AssertExpectations(t)
+}
+
+func randomUnit() *models.Unit {
+	buf := make([]byte, 50)
+	rand.Read(buf)
+	return &models.Unit{
+		ID:        uuid.New().String(),
+		Data:      buf,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+}
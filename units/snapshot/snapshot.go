@@ -0,0 +1,286 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+)
+
+// Version is the version of the snapshot format, snapshots of other
+// versions are not loaded.
+const Version uint32 = 1
+
+var magic = [4]byte{'A', 'R', 'T', 'S'}
+
+var (
+	ErrCorrupt      = errors.New("snapshot is corrupt")
+	ErrIncompatible = errors.New("snapshot format is incompatible")
+)
+
+// Snapshot is a copy of units taken at Watermark, changes after
+// Watermark are fetched by a delta sync once the snapshot is loaded.
+type Snapshot struct {
+	Watermark time.Time
+	TakenAt   time.Time
+	Units     models.Units
+}
+
+// The format is
+//
+//	magic [4]byte | version uint32 | watermark int64 | taken at int64 | count uint64
+//	count times: id length uvarint | id | data length uvarint | data | created at varint | updated at varint
+//	crc32 of everything above uint32
+//
+// Fixed size integers are big endian, times are unix nanoseconds, 0 is zero time.
+
+// Write writes snap to w in the snapshot format.
+func Write(w io.Writer, snap *Snapshot) error {
+	crc := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	e := encoder{w: bw}
+
+	e.bytes(magic[:])
+	e.uint32(Version)
+	e.int64(unixNano(snap.Watermark))
+	e.int64(unixNano(snap.TakenAt))
+	e.uint64(uint64(len(snap.Units)))
+	for _, unit := range snap.Units {
+		e.uvarint(uint64(len(unit.ID)))
+		e.bytes([]byte(unit.ID))
+		e.uvarint(uint64(len(unit.Data)))
+		e.bytes(unit.Data)
+		e.varint(unixNano(unit.CreatedAt))
+		e.varint(unixNano(unit.UpdatedAt))
+	}
+	if e.err != nil {
+		return e.err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	_, err := w.Write(sum[:])
+	return err
+}
+
+// Read reads a snapshot written by Write. size is the size of the snapshot
+// in bytes, lengths that don't fit into it are reported as ErrCorrupt.
+func Read(r io.Reader, size int64) (*Snapshot, error) {
+	crc := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	d := decoder{r: bufio.NewReader(r), crc: crc, left: size}
+
+	var header [4]byte
+	d.read(header[:])
+	if d.err != nil || header != magic {
+		return nil, fmt.Errorf("%w: unknown header", ErrCorrupt)
+	}
+	if version := d.uint32(); d.err == nil && version != Version {
+		return nil, fmt.Errorf("%w: version %d, supported %d", ErrIncompatible, version, Version)
+	}
+
+	snap := &Snapshot{
+		Watermark: fromUnixNano(d.int64()),
+		TakenAt:   fromUnixNano(d.int64()),
+	}
+	count := d.uint64()
+	// every unit takes at least 4 bytes, so a corrupt count doesn't allocate much
+	if d.err == nil && count > uint64(d.left/4) {
+		return nil, fmt.Errorf("%w: %d units don't fit into %d bytes", ErrCorrupt, count, size)
+	}
+	snap.Units = make(models.Units, 0, count)
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		unit := &models.Unit{}
+		unit.ID = string(d.lengthPrefixed())
+		unit.Data = d.lengthPrefixed()
+		unit.CreatedAt = fromUnixNano(d.varint())
+		unit.UpdatedAt = fromUnixNano(d.varint())
+		snap.Units = append(snap.Units, unit)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, d.err)
+	}
+
+	expected := crc.Sum32()
+	var sum [4]byte
+	if _, err := io.ReadFull(d.r, sum[:]); err != nil {
+		return nil, fmt.Errorf("%w: no checksum", ErrCorrupt)
+	}
+	if binary.BigEndian.Uint32(sum[:]) != expected {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+	return snap, nil
+}
+
+// Save writes snap to path atomically, a snapshot being written
+// never replaces the previous one partially.
+func Save(path string, snap *Snapshot) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = Write(f, snap)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load reads the snapshot saved at path.
+func Load(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(f, info.Size())
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) uint32(v uint32) {
+	binary.BigEndian.PutUint32(e.buf[:], v)
+	e.bytes(e.buf[:4])
+}
+
+func (e *encoder) uint64(v uint64) {
+	binary.BigEndian.PutUint64(e.buf[:], v)
+	e.bytes(e.buf[:8])
+}
+
+func (e *encoder) int64(v int64) {
+	e.uint64(uint64(v))
+}
+
+func (e *encoder) uvarint(v uint64) {
+	e.bytes(e.buf[:binary.PutUvarint(e.buf[:], v)])
+}
+
+func (e *encoder) varint(v int64) {
+	e.bytes(e.buf[:binary.PutVarint(e.buf[:], v)])
+}
+
+// decoder reads fields and feeds them to crc, the first error stops reading.
+type decoder struct {
+	r    *bufio.Reader
+	crc  hash.Hash32
+	left int64
+	buf  [8]byte
+	err  error
+}
+
+func (d *decoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	if int64(len(b)) > d.left {
+		d.err = io.ErrUnexpectedEOF
+		return
+	}
+	_, d.err = io.ReadFull(d.r, b)
+	d.crc.Write(b)
+	d.left -= int64(len(b))
+}
+
+func (d *decoder) ReadByte() (byte, error) {
+	d.read(d.buf[:1])
+	return d.buf[0], d.err
+}
+
+func (d *decoder) uint32() uint32 {
+	d.read(d.buf[:4])
+	return binary.BigEndian.Uint32(d.buf[:4])
+}
+
+func (d *decoder) uint64() uint64 {
+	d.read(d.buf[:8])
+	return binary.BigEndian.Uint64(d.buf[:8])
+}
+
+func (d *decoder) int64() int64 {
+	return int64(d.uint64())
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d)
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d)
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+	return v
+}
+
+func (d *decoder) lengthPrefixed() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(d.left) {
+		d.err = fmt.Errorf("length %d exceeds the rest of the snapshot", n)
+		return nil
+	}
+	b := make([]byte, n)
+	d.read(b)
+	return b
+}
+
+// unixNano keeps zero time as 0, so it's restored as zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
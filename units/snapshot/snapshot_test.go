@@ -0,0 +1,128 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "units.snapshot")
+	snap := randomSnapshot(100)
+
+	err := Save(path, snap)
+	require.NoError(t, err)
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, snap, loaded)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_Read_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, randomSnapshot(10))
+	require.NoError(t, err)
+	data := buf.Bytes()
+
+	for name, corrupt := range map[string]func([]byte) []byte{
+		"flipped bit": func(b []byte) []byte {
+			b[len(b)/2] ^= 1
+			return b
+		},
+		"truncated": func(b []byte) []byte {
+			return b[:len(b)-10]
+		},
+		"no checksum": func(b []byte) []byte {
+			return b[:len(b)-4]
+		},
+		"huge count": func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[24:32], 1<<60)
+			return b
+		},
+		"unknown header": func(b []byte) []byte {
+			return append([]byte("{}"), b...)
+		},
+		"empty": func(b []byte) []byte {
+			return nil
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := corrupt(append([]byte(nil), data...))
+			_, err := Read(bytes.NewReader(b), int64(len(b)))
+			require.ErrorIs(t, err, ErrCorrupt)
+		})
+	}
+}
+
+func Test_Read_Incompatible(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, randomSnapshot(1))
+	require.NoError(t, err)
+
+	b := buf.Bytes()
+	binary.BigEndian.PutUint32(b[4:8], Version+1)
+	_, err = Read(bytes.NewReader(b), int64(len(b)))
+	require.ErrorIs(t, err, ErrIncompatible)
+}
+
+type units models.Units
+
+func (u units) ResidentUnits() models.Units {
+	return models.Units(u)
+}
+
+func Test_Writer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "units.snapshot")
+	snap := randomSnapshot(3)
+
+	var watermark time.Time
+	w := NewWriter(path, units(snap.Units), func() time.Time { return watermark })
+	w.now = func() time.Time { return snap.TakenAt }
+
+	// nothing is written before the first sync
+	written, err := w.Write()
+	require.NoError(t, err)
+	require.Nil(t, written)
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	watermark = snap.Watermark
+	written, err = w.Write()
+	require.NoError(t, err)
+	require.Equal(t, snap, written)
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, snap, loaded)
+}
+
+func randomSnapshot(n int) *Snapshot {
+	now := time.Now().UTC()
+	snap := &Snapshot{
+		Watermark: now,
+		TakenAt:   now.Add(time.Second),
+		Units:     make(models.Units, 0, n),
+	}
+	for i := 0; i < n; i++ {
+		data := make([]byte, 50)
+		rand.Read(data)
+		snap.Units = append(snap.Units, &models.Unit{
+			ID:        uuid.New().String(),
+			Data:      data,
+			CreatedAt: now.Add(-time.Hour),
+			UpdatedAt: now.Add(-time.Duration(i) * time.Second),
+		})
+	}
+	return snap
+}
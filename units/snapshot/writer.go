@@ -0,0 +1,81 @@
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/rs/zerolog"
+)
+
+// Source is a layer whose units are saved in snapshots.
+type Source interface {
+	// ResidentUnits returns units kept in memory with their data.
+	ResidentUnits() models.Units
+}
+
+// Writer periodically saves units of a source to a file.
+type Writer struct {
+	path      string
+	source    Source
+	watermark func() time.Time
+	now       func() time.Time
+}
+
+// NewWriter returns a writer of snapshots of source to path. watermark
+// returns the latest change applied to source, it is read before units,
+// so the snapshot doesn't claim changes it may miss.
+func NewWriter(path string, source Source, watermark func() time.Time) *Writer {
+	return &Writer{
+		path:      path,
+		source:    source,
+		watermark: watermark,
+		now:       time.Now,
+	}
+}
+
+// Write saves a snapshot, nothing is saved until the source is synced.
+func (w *Writer) Write() (*Snapshot, error) {
+	watermark := w.watermark()
+	if watermark.IsZero() {
+		return nil, nil
+	}
+	snap := &Snapshot{
+		Watermark: watermark,
+		TakenAt:   w.now(),
+		Units:     w.source.ResidentUnits(),
+	}
+	return snap, Save(w.path, snap)
+}
+
+// WriteSometimes saves a snapshot every interval until ctx is done.
+func (w *Writer) WriteSometimes(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger := zerolog.Ctx(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			snap, err := w.Write()
+			if err != nil {
+				logger.Error().Err(err).Str("path", w.path).Msg("write snapshot")
+				continue
+			}
+			if snap != nil {
+				logger.Info().
+					Str("path", w.path).
+					Int("units", len(snap.Units)).
+					Time("watermark", snap.Watermark).
+					Dur("duration", time.Since(start)).
+					Msg("snapshot written")
+			}
+		}
+	}
+}
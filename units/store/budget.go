@@ -0,0 +1,153 @@
+package store
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/AltMax/art-test/units"
+)
+
+// EvictionPolicy defines what happens with entries when the store exceeds
+// its byte budget. Evicted entries are chosen in order of saving.
+type EvictionPolicy string
+
+const (
+	// Spill drops data of entries but keeps their ids and sizes,
+	// data is loaded again on the next read.
+	Spill EvictionPolicy = "spill"
+	// Evict removes entries completely.
+	Evict EvictionPolicy = "evict"
+)
+
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch policy := EvictionPolicy(s); policy {
+	case Spill, Evict:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown store eviction policy %q", s)
+	}
+}
+
+// Stats describes memory used by the store.
+type Stats struct {
+	Entries  int
+	Resident int
+	// DataBytes is the size of data of resident units.
+	DataBytes int64
+	// Bytes is the memory accounted for all entries, DataBytes included.
+	Bytes     int64
+	MaxBytes  int64
+	Evictions uint64
+	// LastReconciliation is the result of the last FetchAll.
+	LastReconciliation units.Reconciliation
+}
+
+func (s *Store) Stats() Stats {
+	stats := Stats{
+		MaxBytes:  s.maxBytes,
+		Evictions: atomic.LoadUint64(&s.evictions),
+	}
+	for _, sh := range s.shards {
+		sh.RLock()
+		stats.Entries += len(sh.entries)
+		stats.Resident += sh.resident
+		stats.DataBytes += sh.dataBytes
+		stats.Bytes += sh.bytes
+		sh.RUnlock()
+	}
+
+	s.reconciliationMu.Lock()
+	stats.LastReconciliation = s.lastReconciliation
+	s.reconciliationMu.Unlock()
+
+	return stats
+}
+
+// Bytes is the memory accounted for the store.
+func (s *Store) Bytes() int64 {
+	return atomic.LoadInt64(&s.bytes)
+}
+
+// evict queues ids of entries that became resident and frees memory
+// until the store fits into its budget. Shards must not be locked.
+func (s *Store) evict(queued ...string) {
+	if s.maxBytes <= 0 {
+		return
+	}
+
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+	s.residentQueue = append(s.residentQueue, queued...)
+
+	for s.Bytes() > s.maxBytes && len(s.residentQueue) > 0 {
+		id := s.residentQueue[0]
+		s.residentQueue[0] = ""
+		s.residentQueue = s.residentQueue[1:]
+		s.evictID(id)
+	}
+
+	// spilled entries are still accounted, so the budget may be exceeded
+	// by ids alone, they are dropped as the last resort
+	for _, sh := range s.shards {
+		if s.Bytes() <= s.maxBytes {
+			break
+		}
+		sh.Lock()
+		for id := range sh.entries {
+			if s.Bytes() <= s.maxBytes {
+				break
+			}
+			s.remove(sh, id)
+			atomic.AddUint64(&s.evictions, 1)
+		}
+		sh.Unlock()
+	}
+
+	s.compactQueue()
+}
+
+// evictID spills or removes the entry if it's still resident.
+func (s *Store) evictID(id string) {
+	sh := s.shard(id)
+	sh.Lock()
+	defer sh.Unlock()
+
+	e, ok := sh.entries[id]
+	if !ok || !e.resident {
+		return
+	}
+	atomic.AddUint64(&s.evictions, 1)
+	switch s.evictionPolicy {
+	case Evict:
+		s.remove(sh, id)
+	default:
+		s.put(sh, id, e.spilled())
+	}
+}
+
+// compactQueue drops stale ids from residentQueue when they dominate it,
+// must be called under evictMu.
+func (s *Store) compactQueue() {
+	resident := int(atomic.LoadInt64(&s.resident))
+	if len(s.residentQueue) <= 2*resident+16 {
+		return
+	}
+	queue := make([]string, 0, resident)
+	seen := make(map[string]struct{}, resident)
+	for _, id := range s.residentQueue {
+		if _, isDuplicate := seen[id]; isDuplicate || !s.isResident(id) {
+			continue
+		}
+		seen[id] = struct{}{}
+		queue = append(queue, id)
+	}
+	s.residentQueue = queue
+}
+
+func (s *Store) isResident(id string) bool {
+	sh := s.shard(id)
+	sh.RLock()
+	defer sh.RUnlock()
+	e, ok := sh.entries[id]
+	return ok && e.resident
+}
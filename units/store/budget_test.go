@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Budget_Spill(t *testing.T) {
+	unitsMock := &mocks.Units{}
+
+	units := models.Units{randomUnit(), randomUnit(), randomUnit()}
+	unitSize := newEntry(units[0]).bytes(units[0].ID)
+	spilledSize := newLazyEntry(units[0]).bytes(units[0].ID)
+	testStore := NewStore(unitsMock, WithMaxBytes(2*unitSize+spilledSize))
+
+	testStore.saveUnits(units...)
+
+	stats := testStore.Stats()
+	require.Equal(t, 3, stats.Entries)
+	require.Equal(t, 2, stats.Resident)
+	require.Equal(t, uint64(1), stats.Evictions)
+	require.LessOrEqual(t, stats.Bytes, stats.MaxBytes)
+	require.Equal(t, int64(2*len(units[0].Data)), stats.DataBytes)
+
+	// the oldest unit is spilled and loaded from the next layer
+	require.Nil(t, testStore.getByID(units[0].ID))
+	unitsMock.On("FindByID", mock.Anything, units[0].ID).Return(units[0], nil).Once()
+	actualUnit, err := testStore.FindByID(context.Background(), units[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, units[0], actualUnit)
+	require.Equal(t, 3, testStore.Len())
+	require.Equal(t, 2, testStore.Stats().Resident)
+}
+
+func Test_Budget_Evict(t *testing.T) {
+	unitsMock := &mocks.Units{}
+
+	units := models.Units{randomUnit(), randomUnit(), randomUnit()}
+	unitSize := newEntry(units[0]).bytes(units[0].ID)
+	testStore := NewStore(unitsMock, WithMaxBytes(2*unitSize), WithEvictionPolicy(Evict))
+
+	testStore.saveUnits(units...)
+
+	stats := testStore.Stats()
+	require.Equal(t, 2, stats.Entries)
+	require.Equal(t, 2, stats.Resident)
+	require.Equal(t, 2*unitSize, stats.Bytes)
+	require.Nil(t, testStore.getByID(units[0].ID))
+	require.Equal(t, units[2], testStore.getByID(units[2].ID))
+}
+
+func Test_Budget_Accounting(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testStore := NewStore(unitsMock)
+
+	unit := randomUnit()
+	testStore.saveUnits(unit)
+	testStore.saveUnits(unit)
+	require.Equal(t, newEntry(unit).bytes(unit.ID), testStore.Bytes())
+
+	testStore.Invalidate(unit.ID)
+	require.Equal(t, Stats{}, testStore.Stats())
+}
+
+func Test_Lazy_FetchAll(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testStore := NewStore(unitsMock, WithLazy())
+
+	ctx := context.Background()
+
+	units := models.Units{randomUnit(), randomUnit()}
+	unitsMock.On("FetchAll", mock.Anything).Return(units, nil)
+	_, err := testStore.FetchAll(ctx)
+	require.NoError(t, err)
+
+	stats := testStore.Stats()
+	require.Equal(t, 2, stats.Entries)
+	require.Equal(t, 0, stats.Resident)
+	require.Equal(t, int64(0), stats.DataBytes)
+
+	// data is loaded on the first read only
+	unitsMock.On("FindByID", mock.Anything, units[0].ID).Return(units[0], nil).Once()
+	for i := 0; i < 2; i++ {
+		actualUnit, err := testStore.FindByID(ctx, units[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, units[0], actualUnit)
+	}
+	require.Equal(t, 1, testStore.Stats().Resident)
+
+	// resident units are refreshed by the next sync
+	_, err = testStore.FetchAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, testStore.Stats().Resident)
+}
+
+func Test_compact(t *testing.T) {
+	data := make([]byte, 3, 10)
+	require.Equal(t, 3, cap(compact(data)))
+	require.Equal(t, data, compact(data))
+}
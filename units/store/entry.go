@@ -0,0 +1,90 @@
+package store
+
+import (
+	"time"
+
+	"github.com/AltMax/art-test/models"
+)
+
+// entryOverhead is an estimate of memory used by a map entry besides
+// the id and the data: the entry itself, the id string header and map buckets.
+const entryOverhead = 64
+
+// entry is a compact representation of a unit. Entries that are not resident
+// keep only the size of data, the unit is loaded from the next layer on read.
+type entry struct {
+	data      []byte
+	createdAt int64 // unix nanoseconds
+	updatedAt int64 // unix nanoseconds
+	size      int
+	resident  bool
+}
+
+func newEntry(unit *models.Unit) entry {
+	return entry{
+		data:      compact(unit.Data),
+		createdAt: unit.CreatedAt.UnixNano(),
+		updatedAt: unixNano(unit.UpdatedAt),
+		size:      len(unit.Data),
+		resident:  true,
+	}
+}
+
+func newLazyEntry(unit *models.Unit) entry {
+	return entry{
+		createdAt: unit.CreatedAt.UnixNano(),
+		updatedAt: unixNano(unit.UpdatedAt),
+		size:      len(unit.Data),
+	}
+}
+
+func (e entry) unit(id string) *models.Unit {
+	return &models.Unit{
+		ID:        id,
+		Data:      e.data,
+		CreatedAt: time.Unix(0, e.createdAt).UTC(),
+		UpdatedAt: fromUnixNano(e.updatedAt),
+	}
+}
+
+// newerThan reports whether the entry was written later than unit,
+// entries without UpdatedAt are never newer.
+func (e entry) newerThan(unit *models.Unit) bool {
+	return e.updatedAt > unixNano(unit.UpdatedAt)
+}
+
+// bytes is the memory accounted for the entry with the given id.
+func (e entry) bytes(id string) int64 {
+	return int64(len(id) + entryOverhead + len(e.data))
+}
+
+func (e entry) spilled() entry {
+	e.data = nil
+	e.resident = false
+	return e
+}
+
+// compact drops unused capacity of data, so it doesn't pin larger buffers.
+func compact(data []byte) []byte {
+	if cap(data) == len(data) {
+		return data
+	}
+	compacted := make([]byte, len(data))
+	copy(compacted, data)
+	return compacted
+}
+
+// unixNano keeps zero time as 0, so it's restored as zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/AltMax/art-test/units/racetest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LostUpdates(t *testing.T) {
+	racetest.Run(t,
+		func(next *mocks.Units) units.Units {
+			negative, err := units.NewNegativeCache(10, time.Hour)
+			require.NoError(t, err)
+			return NewStore(next, WithNegativeCache(negative))
+		},
+		func(layer units.Units, id string) *models.Unit {
+			return layer.(*Store).getByID(id)
+		},
+	)
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/AltMax/art-test/models"
+)
+
+const defaultShards = 32
+
+// shard is a part of the store with its own lock, so reads of units of
+// different shards don't wait for each other and for writes.
+type shard struct {
+	sync.RWMutex
+	entries map[string]entry
+
+	bytes     int64
+	dataBytes int64
+	resident  int
+}
+
+func newShard() *shard {
+	return &shard{entries: make(map[string]entry)}
+}
+
+// shard returns the shard of the unit with the id.
+func (s *Store) shard(id string) *shard {
+	return s.shards[s.shardIndex(id)]
+}
+
+// shardIndex hashes id with 32-bit FNV-1a, it doesn't allocate unlike hash/fnv.
+func (s *Store) shardIndex(id string) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(id); i++ {
+		hash ^= uint32(id[i])
+		hash *= 16777619
+	}
+	return int(hash % uint32(len(s.shards)))
+}
+
+// write calls fn for every unit with its shard locked, each shard is locked
+// once. fn reports whether the unit became resident, resident units are
+// queued for eviction in the order of units.
+func (s *Store) write(units models.Units, fn func(sh *shard, unit *models.Unit) (resident bool)) {
+	resident := make([]bool, len(units))
+	if len(units) == 1 {
+		sh := s.shard(units[0].ID)
+		sh.Lock()
+		resident[0] = fn(sh, units[0])
+		sh.Unlock()
+	} else {
+		groups := make([][]int, len(s.shards))
+		for i, unit := range units {
+			shardIndex := s.shardIndex(unit.ID)
+			groups[shardIndex] = append(groups[shardIndex], i)
+		}
+		for shardIndex, group := range groups {
+			if len(group) == 0 {
+				continue
+			}
+			sh := s.shards[shardIndex]
+			sh.Lock()
+			for _, i := range group {
+				resident[i] = fn(sh, units[i])
+			}
+			sh.Unlock()
+		}
+	}
+
+	if s.maxBytes <= 0 {
+		return
+	}
+	queued := make([]string, 0, len(units))
+	for i, unit := range units {
+		if resident[i] {
+			queued = append(queued, unit.ID)
+		}
+	}
+	s.evict(queued...)
+}
+
+// put saves the entry and updates accounting,
+// must be called under the lock of the shard.
+func (s *Store) put(sh *shard, id string, e entry) {
+	s.remove(sh, id)
+	sh.entries[id] = e
+	s.account(sh, id, e, 1)
+}
+
+// remove deletes the entry and updates accounting,
+// must be called under the lock of the shard.
+func (s *Store) remove(sh *shard, id string) bool {
+	e, ok := sh.entries[id]
+	if ok {
+		delete(sh.entries, id)
+		s.account(sh, id, e, -1)
+	}
+	return ok
+}
+
+// removeID removes the unit with the id from its shard,
+// reads in flight don't save it again.
+func (s *Store) removeID(id string) bool {
+	sh := s.shard(id)
+	sh.Lock()
+	defer sh.Unlock()
+	s.removed.Add(id)
+	return s.remove(sh, id)
+}
+
+// account updates totals of the shard and the store.
+func (s *Store) account(sh *shard, id string, e entry, sign int64) {
+	bytes := sign * e.bytes(id)
+	sh.bytes += bytes
+	atomic.AddInt64(&s.bytes, bytes)
+	if e.resident {
+		sh.dataBytes += sign * int64(len(e.data))
+		sh.resident += int(sign)
+		atomic.AddInt64(&s.resident, sign)
+	}
+}
+
+// reset drops all entries of the shard,
+// must be called under the lock of the shard.
+func (s *Store) reset(sh *shard) {
+	atomic.AddInt64(&s.bytes, -sh.bytes)
+	atomic.AddInt64(&s.resident, -int64(sh.resident))
+	sh.entries = make(map[string]entry)
+	sh.bytes, sh.dataBytes, sh.resident = 0, 0, 0
+}
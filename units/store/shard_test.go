@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithShards_NonPositive(t *testing.T) {
+	for _, shards := range []int{0, -1} {
+		testStore := NewStore(&mocks.Units{}, WithShards(shards))
+		require.Len(t, testStore.shards, 1)
+
+		unit := randomUnit()
+		testStore.saveUnits(unit)
+		require.Equal(t, unit, testStore.getByID(unit.ID))
+	}
+}
+
+func Test_Shards_Concurrent(t *testing.T) {
+	stored := make(models.Units, 1000)
+	for i := range stored {
+		stored[i] = randomUnit()
+	}
+	unitSize := newEntry(stored[0]).bytes(stored[0].ID)
+	testStore := NewStore(&mocks.Units{}, WithShards(8), WithMaxBytes(500*unitSize))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(stored); i += 4 {
+				testStore.saveUnits(stored[i])
+				testStore.getByIDs([]string{stored[i].ID, stored[len(stored)-1-i].ID})
+			}
+			testStore.Invalidate(stored[w].ID)
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fetched := make(map[string]struct{})
+		testStore.applyFetched(0, stored, fetched, &units.Reconciliation{})
+	}()
+	wg.Wait()
+
+	stats := testStore.Stats()
+	require.Equal(t, testStore.Len(), stats.Entries)
+	require.Equal(t, testStore.Bytes(), stats.Bytes)
+	require.LessOrEqual(t, stats.Bytes, stats.MaxBytes)
+	require.Len(t, testStore.ResidentUnits(), stats.Resident)
+}
+
+// Benchmark_FindByID_DuringSync measures latency of reads while a full sync
+// saves all units again, run it with -race to check the locking as well.
+// p99 shows the effect of shards only with several CPUs, with one CPU it's
+// mostly the time readers wait to be scheduled.
+func Benchmark_FindByID_DuringSync(b *testing.B) {
+	stored := make(models.Units, 100000)
+	ids := make([]string, len(stored))
+	for i := range stored {
+		stored[i] = randomUnit()
+		ids[i] = stored[i].ID
+	}
+
+	for _, shards := range []int{1, defaultShards} {
+		for _, lookup := range []int{1, 20} {
+			b.Run(fmt.Sprintf("shards=%d/ids=%d", shards, lookup), func(b *testing.B) {
+				testStore := NewStore(&mocks.Units{}, WithShards(shards))
+				testStore.saveUnits(stored...)
+
+				stop := make(chan struct{})
+				synced := make(chan struct{})
+				go func() {
+					defer close(synced)
+					for {
+						for i := 0; i < len(stored); i += 1000 {
+							select {
+							case <-stop:
+								return
+							default:
+							}
+							testStore.applyFetched(0, stored[i:i+1000], make(map[string]struct{}), &units.Reconciliation{})
+						}
+					}
+				}()
+
+				ctx := context.Background()
+				var (
+					mu        sync.Mutex
+					latencies []time.Duration
+				)
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+					local := make([]time.Duration, 0, 1024)
+					batch := make([]string, lookup)
+					for pb.Next() {
+						for i := range batch {
+							batch[i] = ids[rnd.Intn(len(ids))]
+						}
+						start := time.Now()
+						if lookup == 1 {
+							_, _ = testStore.FindByID(ctx, batch[0])
+						} else {
+							_, _ = testStore.FindByIDs(ctx, batch)
+						}
+						local = append(local, time.Since(start))
+					}
+					mu.Lock()
+					latencies = append(latencies, local...)
+					mu.Unlock()
+				})
+				b.StopTimer()
+				close(stop)
+				<-synced
+
+				sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+				if len(latencies) > 0 {
+					b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+				}
+			})
+		}
+	}
+}
@@ -2,31 +2,134 @@ package store
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/AltMax/art-test/models"
 	"github.com/AltMax/art-test/units"
 )
 
+// Store keeps all units in memory. Units are spread over shards with their
+// own locks, so writes and bulk loads by syncs block reads of one shard
+// at a time only.
 type Store struct {
 	units.Units
-	sync.RWMutex
-	store map[string]*models.Unit
+	shards []*shard
+
+	maxBytes       int64
+	lazy           bool
+	evictionPolicy EvictionPolicy
+
+	negative *units.NegativeCache
+	// removed keeps units removed while reads of the next layer are in
+	// flight, so reads don't save units deleted after they were read
+	removed *units.Tombstones
+
+	maxRemovedRatio      float64
+	removalConfirmations int
+	removals             *units.RemovalGuard
+	reconciliationMu     sync.Mutex
+	lastReconciliation   units.Reconciliation
+
+	// bytes and resident are totals of all shards,
+	// they are updated atomically under locks of shards
+	bytes     int64
+	resident  int64
+	evictions uint64
+	// evictMu guards residentQueue and serializes evictions,
+	// shards are locked under it and never the other way round
+	evictMu sync.Mutex
+	// residentQueue holds ids of resident entries in order of saving,
+	// it may contain ids that are not resident anymore. It's only kept
+	// when the store has a byte budget.
+	residentQueue []string
+}
+
+type Option func(*Store)
+
+// WithShards sets the number of shards, more shards make
+// concurrent reads and writes wait for each other less.
+// Less than one shard means a single one.
+func WithShards(n int) Option {
+	return func(s *Store) {
+		if n < 1 {
+			n = 1
+		}
+		s.shards = make([]*shard, n)
+	}
+}
+
+// WithMaxBytes limits the memory accounted for stored units,
+// entries are evicted according to the eviction policy when it is exceeded.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(s *Store) {
+		s.maxBytes = maxBytes
+	}
+}
+
+// WithLazy makes FetchAll keep only ids and sizes of units,
+// data is loaded from the next layer on first read.
+func WithLazy() Option {
+	return func(s *Store) {
+		s.lazy = true
+	}
 }
 
-func NewStore(dao units.Units) *Store {
-	return &Store{
-		Units: dao,
-		store: make(map[string]*models.Unit),
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(s *Store) {
+		s.evictionPolicy = policy
 	}
 }
 
+// WithMaxRemovedRatio skips removal of units missing from a full fetch when
+// the fetch has shrunk by more than ratio compared with the previous one,
+// unless confirmations consecutive fetches agree on the smaller total.
+func WithMaxRemovedRatio(ratio float64, confirmations int) Option {
+	return func(s *Store) {
+		s.maxRemovedRatio = ratio
+		s.removalConfirmations = confirmations
+	}
+}
+
+// WithNegativeCache makes the store remember ids that were not found,
+// so they are not looked up in the next layer again until they expire.
+func WithNegativeCache(negative *units.NegativeCache) Option {
+	return func(s *Store) {
+		s.negative = negative
+	}
+}
+
+func NewStore(dao units.Units, opts ...Option) *Store {
+	s := &Store{
+		Units:                dao,
+		shards:               make([]*shard, defaultShards),
+		evictionPolicy:       Spill,
+		maxRemovedRatio:      units.DefaultMaxRemovedRatio,
+		removalConfirmations: units.DefaultRemovalConfirmations,
+		removed:              units.NewTombstones(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.removals = units.NewRemovalGuard(s.maxRemovedRatio, s.removalConfirmations)
+	if len(s.shards) == 0 {
+		s.shards = make([]*shard, 1)
+	}
+	for i := range s.shards {
+		s.shards[i] = newShard()
+	}
+	return s
+}
+
 func (s *Store) Create(ctx context.Context, unit *models.Unit) error {
 	err := s.Units.Create(ctx, unit)
 	if err != nil {
 		return err
 	}
 
+	s.negative.Remove(unit.ID)
 	s.saveUnits(unit)
 
 	return nil
@@ -38,6 +141,7 @@ func (s *Store) Update(ctx context.Context, id string, data []byte) (*models.Uni
 		return nil, err
 	}
 
+	s.negative.Remove(id)
 	s.saveUnits(updatedUnit)
 
 	return updatedUnit, nil
@@ -46,10 +150,11 @@ func (s *Store) Update(ctx context.Context, id string, data []byte) (*models.Uni
 func (s *Store) Delete(ctx context.Context, id string) error {
 	err := s.Units.Delete(ctx, id)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	s.removeUnit(id)
+	s.negative.Add(id)
 
 	return nil
 }
@@ -59,64 +164,312 @@ func (s *Store) FindByID(ctx context.Context, id string) (u *models.Unit, err er
 	if u != nil {
 		return u, nil
 	}
+	if s.negative.Contains(id) {
+		return nil, units.ErrNotFound
+	}
 
+	start := s.removed.Begin()
+	defer s.removed.End(start)
+	gen := s.negative.Generation()
 	u, err = s.Units.FindByID(ctx, id)
+	if errors.Is(err, units.ErrNotFound) {
+		s.negative.AddSince(gen, id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	s.saveUnits(u)
+	s.saveRead(start, u)
 
 	return u, nil
 }
 
 func (s *Store) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
-	return units.FindByIDs(ctx, ids, s.getByIDs, s.saveUnits, s.Units)
+	start := s.removed.Begin()
+	defer s.removed.End(start)
+	saveRead := func(found ...*models.Unit) {
+		s.saveRead(start, found...)
+	}
+	return units.FindByIDs(ctx, ids, s.getByIDs, saveRead, s.Units, s.negative)
 }
 
-func (s *Store) saveUnits(units ...*models.Unit) {
-	s.Lock()
-	defer s.Unlock()
+// Put saves units written bypassing the next layer, they replace
+// stored versions whatever their UpdatedAt is.
+func (s *Store) Put(units ...*models.Unit) {
 	for _, unit := range units {
-		s.store[unit.ID] = unit
+		s.negative.Remove(unit.ID)
+	}
+	s.write(units, func(sh *shard, unit *models.Unit) bool {
+		s.put(sh, unit.ID, newEntry(unit))
+		return true
+	})
+}
+
+// Save stores units the next layer wrote later, with the versions it
+// assigned, unless newer versions of them are stored already.
+func (s *Store) Save(units ...*models.Unit) {
+	s.saveUnits(units...)
+}
+
+// Contains reports whether the unit is stored, resident or not,
+// the next layer is not read.
+func (s *Store) Contains(id string) bool {
+	sh := s.shard(id)
+	sh.RLock()
+	defer sh.RUnlock()
+	_, ok := sh.entries[id]
+	return ok
+}
+
+// saveUnits stores units unless newer versions of them are stored already,
+// so a unit read before a concurrent write doesn't overwrite the written one.
+func (s *Store) saveUnits(units ...*models.Unit) {
+	s.write(units, func(sh *shard, unit *models.Unit) bool {
+		if e, ok := sh.entries[unit.ID]; ok && e.newerThan(unit) {
+			return false
+		}
+		s.put(sh, unit.ID, newEntry(unit))
+		return true
+	})
+}
+
+// saveRead is saveUnits for units read by a read of the next layer that
+// began at start, units removed since are not saved.
+func (s *Store) saveRead(start uint64, units ...*models.Unit) {
+	s.write(units, func(sh *shard, unit *models.Unit) bool {
+		if s.removed.Removed(unit.ID, start) {
+			return false
+		}
+		if e, ok := sh.entries[unit.ID]; ok && e.newerThan(unit) {
+			return false
+		}
+		s.put(sh, unit.ID, newEntry(unit))
+		return true
+	})
+}
+
+// applyChanges saves updated units and removes deleted ones,
+// see saveRead for start.
+func (s *Store) applyChanges(start uint64, changes *models.Changes) {
+	for _, unit := range changes.Updated {
+		s.negative.Remove(unit.ID)
+	}
+	s.write(changes.Updated, func(sh *shard, unit *models.Unit) bool {
+		if s.removed.Removed(unit.ID, start) {
+			return false
+		}
+		_, _, resident := s.apply(sh, unit)
+		return resident
+	})
+	for _, id := range changes.Deleted {
+		s.removeID(id)
 	}
 }
 
+// applyFetched saves a batch of a full fetch, ids of its units are added
+// to fetched and the counts to r. See saveRead for start.
+func (s *Store) applyFetched(start uint64, batch models.Units, fetched map[string]struct{}, r *units.Reconciliation) {
+	for _, unit := range batch {
+		fetched[unit.ID] = struct{}{}
+	}
+	s.write(batch, func(sh *shard, unit *models.Unit) bool {
+		if s.removed.Removed(unit.ID, start) {
+			return false
+		}
+		added, updated, resident := s.apply(sh, unit)
+		if added {
+			r.Added++
+		} else if updated {
+			r.Updated++
+		}
+		return resident
+	})
+}
+
+// removeVanished removes units that were stored before a full fetch
+// but are missing from it.
+func (s *Store) removeVanished(before []string, fetched map[string]struct{}, r *units.Reconciliation) {
+	var vanished []string
+	if s.removals.Accept(len(fetched)) {
+		vanished = units.Vanished(before, fetched)
+	} else {
+		r.Skipped = true
+	}
+	for _, id := range vanished {
+		if s.removeID(id) {
+			r.Removed++
+		}
+	}
+
+	s.reconciliationMu.Lock()
+	s.lastReconciliation = *r
+	s.reconciliationMu.Unlock()
+}
+
+// apply saves a unit fetched by a sync. Units that are older than the stored
+// ones are skipped, so a sync read before a concurrent write doesn't overwrite
+// it. Lazy stores keep only sizes of units that are not resident yet.
+// Must be called under the lock of the shard.
+func (s *Store) apply(sh *shard, unit *models.Unit) (added, updated, resident bool) {
+	e, ok := sh.entries[unit.ID]
+	switch {
+	case ok && e.newerThan(unit):
+		// a newer write is already stored
+		return false, false, false
+	case s.lazy && !(ok && e.resident):
+		s.put(sh, unit.ID, newLazyEntry(unit))
+	default:
+		s.put(sh, unit.ID, newEntry(unit))
+		resident = true
+	}
+	return !ok, ok && (e.updatedAt != unixNano(unit.UpdatedAt) || e.size != len(unit.Data)), resident
+}
+
+// ResidentUnits returns stored units that have their data in memory,
+// units spilled or not loaded yet are not returned.
+func (s *Store) ResidentUnits() models.Units {
+	units := make(models.Units, 0, atomic.LoadInt64(&s.resident))
+	for _, sh := range s.shards {
+		sh.RLock()
+		for id, e := range sh.entries {
+			if e.resident {
+				units = append(units, e.unit(id))
+			}
+		}
+		sh.RUnlock()
+	}
+	return units
+}
+
+// Restore saves units of a snapshot, units that are stored already and are
+// newer than the snapshot ones are kept.
+func (s *Store) Restore(units models.Units) {
+	s.write(units, func(sh *shard, unit *models.Unit) bool {
+		_, _, resident := s.apply(sh, unit)
+		return resident
+	})
+	s.removals.Seed(s.Len())
+}
+
+// ids returns ids of all stored units.
+func (s *Store) ids() []string {
+	ids := make([]string, 0, s.Len())
+	for _, sh := range s.shards {
+		sh.RLock()
+		for id := range sh.entries {
+			ids = append(ids, id)
+		}
+		sh.RUnlock()
+	}
+	return ids
+}
+
 func (s *Store) removeUnit(id string) {
-	s.Lock()
-	defer s.Unlock()
-	delete(s.store, id)
+	s.Invalidate(id)
+}
+
+func (s *Store) Invalidate(ids ...string) {
+	for _, id := range ids {
+		s.removeID(id)
+	}
+	s.negative.Remove(ids...)
+}
+
+// InvalidateAll removes all units, reads in flight don't store them again.
+func (s *Store) InvalidateAll() {
+	s.removed.AddAll()
+	for _, sh := range s.shards {
+		sh.Lock()
+		s.reset(sh)
+		sh.Unlock()
+	}
+	s.evictMu.Lock()
+	s.residentQueue = nil
+	s.evictMu.Unlock()
+	s.negative.Purge()
+}
+
+func (s *Store) NegativeStats() units.NegativeStats {
+	return s.negative.Stats()
+}
+
+func (s *Store) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.RLock()
+		n += len(sh.entries)
+		sh.RUnlock()
+	}
+	return n
 }
 
 func (s *Store) getByID(id string) *models.Unit {
-	s.RLock()
-	defer s.RUnlock()
-	if t, ok := s.store[id]; ok {
-		return t
+	sh := s.shard(id)
+	sh.RLock()
+	defer sh.RUnlock()
+	if e, ok := sh.entries[id]; ok && e.resident {
+		return e.unit(id)
 	}
 	return nil
 }
 
+// getByIDs locks shards one id at a time, so a long list
+// doesn't keep writes of all shards waiting.
 func (s *Store) getByIDs(ids []string) []*models.Unit {
-	s.RLock()
-	defer s.RUnlock()
-
 	units := make([]*models.Unit, 0, len(ids))
 
 	for _, id := range ids {
-		if u, ok := s.store[id]; ok {
-			units = append(units, u)
+		if unit := s.getByID(id); unit != nil {
+			units = append(units, unit)
 		}
 	}
 
 	return units
 }
 
+// FetchAll reconciles the store with all units of the next layer,
+// units that are missing from the result are removed.
 func (s *Store) FetchAll(ctx context.Context) (models.Units, error) {
-	units, err := s.Units.FetchAll(ctx)
+	var fetched models.Units
+	err := s.Scan(ctx, func(batch models.Units) error {
+		fetched = append(fetched, batch...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	s.saveUnits(units...)
-	return units, nil
+	return fetched, nil
+}
+
+// Scan is FetchAll that saves batches as they are scanned from the next
+// layer and passes them to fn, only ids of fetched units are kept.
+// Nothing is removed when the scan fails.
+func (s *Store) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	start := s.removed.Begin()
+	defer s.removed.End(start)
+	before := s.ids()
+	fetched := make(map[string]struct{}, len(before))
+	var r units.Reconciliation
+	err := units.Scan(ctx, s.Units, func(batch models.Units) error {
+		s.applyFetched(start, batch, fetched, &r)
+		return fn(batch)
+	})
+	if err != nil {
+		return err
+	}
+	s.negative.Purge()
+	s.removeVanished(before, fetched, &r)
+	units.LogReconciliation(ctx, "store", r)
+	return nil
+}
+
+func (s *Store) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	start := s.removed.Begin()
+	defer s.removed.End(start)
+	changes, err := s.Units.FetchChanges(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	s.applyChanges(start, changes)
+	return changes, nil
 }
@@ -3,10 +3,12 @@ package store
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
 	"github.com/AltMax/art-test/units/mocks"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -133,6 +135,163 @@ func Test_FetchAll(t *testing.T) {
 	require.Equal(t, units, models.Units(storedUnits))
 }
 
+func Test_FetchAll_Reconcile(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testStore := NewStore(unitsMock)
+
+	ctx := context.Background()
+
+	kept, updated, vanished, added := randomUnit(), randomUnit(), randomUnit(), randomUnit()
+	testStore.saveUnits(kept, updated, vanished)
+
+	updatedCopy := *updated
+	updatedCopy.Data = []byte("updated data")
+	fetched := models.Units{kept, &updatedCopy, added}
+
+	unitsMock.On("FetchAll", mock.Anything).Return(fetched, nil).Once()
+	_, err := testStore.FetchAll(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, testStore.Len())
+	require.Nil(t, testStore.getByID(vanished.ID))
+	require.Equal(t, &updatedCopy, testStore.getByID(updated.ID))
+	require.Equal(t, units.Reconciliation{Added: 1, Updated: 1, Removed: 1}, testStore.Stats().LastReconciliation)
+
+	// an empty result doesn't wipe the store
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{}, nil).Once()
+	_, err = testStore.FetchAll(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, testStore.Len())
+	require.Equal(t, units.Reconciliation{Skipped: true}, testStore.Stats().LastReconciliation)
+}
+
+func Test_FetchAll_Reconcile_SmallStore(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testStore := NewStore(unitsMock)
+	ctx := context.Background()
+
+	unit := randomUnit()
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{unit}, nil).Once()
+	_, err := testStore.FetchAll(ctx)
+	require.NoError(t, err)
+
+	// the only unit is deleted, removal is applied once fetches agree on it
+	unitsMock.On("FetchAll", mock.Anything).Return(models.Units{}, nil)
+	for i := 1; i < units.DefaultRemovalConfirmations; i++ {
+		_, err = testStore.FetchAll(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, testStore.Len())
+	}
+	_, err = testStore.FetchAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, testStore.Len())
+	require.Equal(t, units.Reconciliation{Removed: 1}, testStore.Stats().LastReconciliation)
+}
+
+// scanner is the next layer that streams units by batches.
+type scanner struct {
+	*mocks.Units
+	batches []models.Units
+}
+
+func (s scanner) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	for _, batch := range s.batches {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_Scan(t *testing.T) {
+	kept, vanished := randomUnit(), randomUnit()
+	next := scanner{Units: &mocks.Units{}, batches: []models.Units{
+		{randomUnit(), randomUnit()},
+		{randomUnit(), kept},
+	}}
+	testStore := NewStore(next)
+	testStore.saveUnits(kept, vanished)
+
+	ctx := context.Background()
+
+	var scanned []models.Units
+	err := testStore.Scan(ctx, func(batch models.Units) error {
+		// batches are saved before they are passed on
+		require.Equal(t, batch, models.Units(testStore.getByIDs(batch.IDs())))
+		scanned = append(scanned, batch)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, next.batches, scanned)
+
+	require.Equal(t, 4, testStore.Len())
+	require.Nil(t, testStore.getByID(vanished.ID))
+	require.Equal(t, units.Reconciliation{Added: 3, Removed: 1}, testStore.Stats().LastReconciliation)
+
+	// nothing is removed when the scan fails
+	testStore.saveUnits(vanished)
+	err = testStore.Scan(ctx, func(batch models.Units) error {
+		return errors.New("failed")
+	})
+	require.Error(t, err)
+	require.Equal(t, vanished, testStore.getByID(vanished.ID))
+}
+
+func Test_Invalidate(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testStore := NewStore(unitsMock)
+
+	units := models.Units{
+		randomUnit(),
+		randomUnit(),
+		randomUnit(),
+	}
+	testStore.saveUnits(units...)
+	require.Equal(t, 3, testStore.Len())
+
+	testStore.Invalidate(units[0].ID, units[1].ID)
+	require.Equal(t, 1, testStore.Len())
+	require.Nil(t, testStore.getByID(units[0].ID))
+	require.Equal(t, units[2], testStore.getByID(units[2].ID))
+
+	testStore.InvalidateAll()
+	require.Equal(t, 0, testStore.Len())
+}
+
+func Test_FetchChanges(t *testing.T) {
+	unitsMock := &mocks.Units{}
+	testStore := NewStore(unitsMock)
+
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	stale, deleted, fresh := randomUnit(), randomUnit(), randomUnit()
+	stale.UpdatedAt = now
+	testStore.saveUnits(stale, deleted)
+
+	olderStale := *stale
+	olderStale.Data = []byte("older data")
+	olderStale.UpdatedAt = now.Add(-time.Second)
+	fresh.UpdatedAt = now
+
+	changes := &models.Changes{
+		Updated:   models.Units{&olderStale, fresh},
+		Deleted:   []string{deleted.ID},
+		Watermark: now,
+	}
+	since := now.Add(-time.Minute)
+	unitsMock.On("FetchChanges", mock.Anything, since).Return(changes, nil)
+	actualChanges, err := testStore.FetchChanges(ctx, since)
+	require.NoError(t, err)
+	require.Equal(t, changes, actualChanges)
+
+	require.Equal(t, stale, testStore.getByID(stale.ID))
+	require.Equal(t, fresh, testStore.getByID(fresh.ID))
+	require.Nil(t, testStore.getByID(deleted.ID))
+	require.Equal(t, 2, testStore.Len())
+}
+
 func randomUnit() *models.Unit {
 	buf := make([]byte, 50)
 	rand.Read(buf)
@@ -142,3 +301,39 @@ func randomUnit() *models.Unit {
 		CreatedAt: time.Now().UTC(),
 	}
 }
+
+func Test_Put(t *testing.T) {
+	testStore := NewStore(&mocks.Units{})
+
+	unit := randomUnit()
+	unit.UpdatedAt = time.Now().UTC()
+	require.False(t, testStore.Contains(unit.ID))
+	testStore.Save(unit)
+	require.True(t, testStore.Contains(unit.ID))
+
+	// units put without a version replace stored versions,
+	// versions saved later replace them
+	written := &models.Unit{ID: unit.ID, Data: []byte("written"), CreatedAt: unit.CreatedAt}
+	testStore.Put(written)
+	require.Equal(t, written, testStore.getByID(unit.ID))
+	testStore.Save(unit)
+	require.Equal(t, unit, testStore.getByID(unit.ID))
+}
+
+func Test_Restore(t *testing.T) {
+	testStore := NewStore(&mocks.Units{})
+
+	unit, newer := randomUnit(), randomUnit()
+	unit.UpdatedAt = time.Now().UTC()
+	newer.UpdatedAt = time.Now().UTC()
+	testStore.saveUnits(newer)
+
+	older := *newer
+	older.Data = []byte("older data")
+	older.UpdatedAt = newer.UpdatedAt.Add(-time.Second)
+	testStore.Restore(models.Units{unit, &older})
+
+	require.Equal(t, unit, testStore.getByID(unit.ID))
+	require.Equal(t, newer, testStore.getByID(newer.ID))
+	require.ElementsMatch(t, models.Units{unit, newer}, testStore.ResidentUnits())
+}
@@ -0,0 +1,114 @@
+package units
+
+import "sync"
+
+// Tombstones remember ids of units removed from a layer while reads of
+// the next layer are in flight, so a read that got a unit before it was
+// deleted doesn't save it to the layer afterwards. A tombstone is dropped
+// once every read that started before it has ended.
+type Tombstones struct {
+	mu  sync.Mutex
+	seq uint64
+	// reads counts reads in flight by the sequence number they started at
+	reads   map[uint64]int
+	removed map[string]uint64
+	// all is the sequence number of the last removal of all units, 0 if none
+	all uint64
+}
+
+func NewTombstones() *Tombstones {
+	return &Tombstones{
+		reads:   make(map[uint64]int),
+		removed: make(map[string]uint64),
+	}
+}
+
+// Begin is called before a read of the next layer, the returned start is
+// passed to Removed and End.
+func (t *Tombstones) Begin() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reads[t.seq]++
+	return t.seq
+}
+
+// End is called when the read that began at start has saved what it read.
+func (t *Tombstones) End(start uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reads[start]--; t.reads[start] > 0 {
+		return
+	}
+	delete(t.reads, start)
+	if len(t.removed) == 0 && t.all == 0 {
+		return
+	}
+	if len(t.reads) == 0 {
+		t.removed = make(map[string]uint64)
+		t.all = 0
+		return
+	}
+
+	oldest := ^uint64(0)
+	for s := range t.reads {
+		if s < oldest {
+			oldest = s
+		}
+	}
+	if oldest < start {
+		// an older read is still in flight and needs the tombstones
+		return
+	}
+	for id, seq := range t.removed {
+		if seq <= oldest {
+			delete(t.removed, id)
+		}
+	}
+	if t.all <= oldest {
+		t.all = 0
+	}
+}
+
+// Add records removal of ids, it's a noop when no read is in flight.
+func (t *Tombstones) Add(ids ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.reads) == 0 || len(ids) == 0 {
+		return
+	}
+	t.seq++
+	for _, id := range ids {
+		t.removed[id] = t.seq
+	}
+}
+
+// AddAll records removal of all units, it's a noop when no read is in flight.
+func (t *Tombstones) AddAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.reads) == 0 {
+		return
+	}
+	t.seq++
+	t.all = t.seq
+	// tombstones of single units are older, so they are not needed anymore
+	t.removed = make(map[string]uint64)
+}
+
+// Removed reports whether id was removed after the read that began at start.
+func (t *Tombstones) Removed(id string, start uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.all > start {
+		return true
+	}
+	seq, ok := t.removed[id]
+	return ok && seq > start
+}
+
+// Len returns the number of tombstones kept.
+func (t *Tombstones) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.removed)
+}
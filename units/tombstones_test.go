@@ -0,0 +1,50 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Tombstones(t *testing.T) {
+	tombstones := NewTombstones()
+
+	// removals made while no read is in flight are not kept
+	tombstones.Add("1")
+	require.Equal(t, 0, tombstones.Len())
+
+	first := tombstones.Begin()
+	tombstones.Add("1")
+	second := tombstones.Begin()
+	tombstones.Add("2")
+	require.True(t, tombstones.Removed("1", first))
+	require.True(t, tombstones.Removed("2", first))
+	require.False(t, tombstones.Removed("1", second))
+	require.True(t, tombstones.Removed("2", second))
+
+	// the tombstone of 1 is only needed by the first read
+	tombstones.End(first)
+	require.Equal(t, 1, tombstones.Len())
+	require.True(t, tombstones.Removed("2", second))
+
+	tombstones.End(second)
+	require.Equal(t, 0, tombstones.Len())
+}
+
+func Test_Tombstones_AddAll(t *testing.T) {
+	tombstones := NewTombstones()
+
+	first := tombstones.Begin()
+	tombstones.AddAll()
+	second := tombstones.Begin()
+	require.True(t, tombstones.Removed("1", first))
+	require.False(t, tombstones.Removed("1", second))
+
+	// removal of all units is kept until the first read ends
+	tombstones.End(second)
+	require.True(t, tombstones.Removed("1", first))
+	tombstones.End(first)
+	third := tombstones.Begin()
+	require.False(t, tombstones.Removed("1", third))
+	tombstones.End(third)
+}
@@ -2,6 +2,8 @@ package units
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/AltMax/art-test/models"
 )
@@ -13,6 +15,67 @@ type Units interface {
 	FindByID(ctx context.Context, id string) (*models.Unit, error)
 	FindByIDs(ctx context.Context, ids []string) (models.Units, error)
 	FetchAll(ctx context.Context) (models.Units, error)
+	// FetchChanges returns units written or deleted at or after since.
+	FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error)
+}
+
+// BatchWriter writes many units with a single round trip.
+type BatchWriter interface {
+	// WriteBatch creates units of created that don't exist yet and updates
+	// units of updated, UpdatedAt of written units is set to their new version.
+	// Ids of updated units that don't exist and of created units that existed
+	// already, which are kept as is, are returned.
+	WriteBatch(ctx context.Context, created, updated models.Units) (notFound, existing []string, err error)
+}
+
+// WriteBatch writes units with next when it's a BatchWriter,
+// it's used by layers that pass batch writes through.
+func WriteBatch(ctx context.Context, next Units, created, updated models.Units) ([]string, []string, error) {
+	writer, ok := next.(BatchWriter)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T doesn't support batch writes", next)
+	}
+	return writer.WriteBatch(ctx, created, updated)
+}
+
+// Scanner streams all units in batches, so they are never held in memory at once.
+type Scanner interface {
+	// Scan calls fn with batches of all units, fn is not called concurrently.
+	// Scan stops and returns the error when fn fails.
+	Scan(ctx context.Context, fn func(batch models.Units) error) error
+}
+
+// Scan streams units of next when it's a Scanner, otherwise
+// all units are fetched with FetchAll and passed to fn at once.
+func Scan(ctx context.Context, next Units, fn func(batch models.Units) error) error {
+	if scanner, ok := next.(Scanner); ok {
+		return scanner.Scan(ctx, fn)
+	}
+	fetched, err := next.FetchAll(ctx)
+	if err != nil {
+		return err
+	}
+	return fn(fetched)
+}
+
+// Layer is an in-memory layer of the units chain
+// that can be inspected and invalidated at runtime.
+type Layer interface {
+	Invalidate(ids ...string)
+	InvalidateAll()
+	Len() int
+}
+
+// SizedLayer is a Layer that accounts memory used by its units.
+type SizedLayer interface {
+	Layer
+	Bytes() int64
+}
+
+// NegativeCachedLayer is a Layer that remembers ids that were not found.
+type NegativeCachedLayer interface {
+	Layer
+	NegativeStats() NegativeStats
 }
 
 func deduplicateIDs(ids []string) []string {
@@ -33,6 +96,7 @@ func FindByIDs(
 	getByIDs func(ids []string) []*models.Unit,
 	saveUnits func(units ...*models.Unit),
 	nextLayer Units,
+	negative *NegativeCache,
 ) (models.Units, error) {
 	uniqueIDs := deduplicateIDs(ids)
 
@@ -53,12 +117,16 @@ func FindByIDs(
 		}
 	}
 
+	missedIDs = negative.unknown(missedIDs)
+
 	if len(missedIDs) > 0 {
+		gen := negative.Generation()
 		dbUnits, err := nextLayer.FindByIDs(ctx, missedIDs)
 		if err != nil {
 			return nil, err
 		}
 		saveUnits(dbUnits...)
+		negative.addNotFound(gen, missedIDs, dbUnits)
 		units = append(units, dbUnits...)
 	}
 
@@ -0,0 +1,30 @@
+package units
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/stretchr/testify/require"
+)
+
+type fetcher struct {
+	Units
+	fetched models.Units
+}
+
+func (f fetcher) FetchAll(ctx context.Context) (models.Units, error) {
+	return f.fetched, nil
+}
+
+func Test_Scan_FetchAll(t *testing.T) {
+	next := fetcher{fetched: models.Units{{ID: "1"}, {ID: "2"}}}
+
+	var scanned []models.Units
+	err := Scan(context.Background(), next, func(batch models.Units) error {
+		scanned = append(scanned, batch)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []models.Units{next.fetched}, scanned)
+}
@@ -0,0 +1,444 @@
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultBatchSize  = 500
+	defaultInterval   = time.Second
+	defaultRetries    = 3
+	defaultMinBackoff = 100 * time.Millisecond
+)
+
+// Store is the in-memory layer writes are saved to before they are flushed.
+type Store interface {
+	units.Units
+	// Put saves units without writing them to the next layer.
+	Put(units ...*models.Unit)
+	// Save stores units written to the next layer with the versions it
+	// assigned, unless newer versions are stored.
+	Save(units ...*models.Unit)
+	// Contains reports whether the unit is stored without reading the next layer.
+	Contains(id string) bool
+	Invalidate(ids ...string)
+}
+
+// WriteBehind acknowledges creates and updates once they are saved to the
+// store and writes them to the database later in batches. Repeated writes of
+// a unit are coalesced into one. Writes not flushed yet are lost if
+// the process dies, so it's meant for units that tolerate it.
+//
+// Deletes are written through, they wait for a flush in progress, so
+// a delete is never followed by an older write of the unit.
+//
+// Pending writes are read before the store, so they are never lost by
+// eviction from the store or by invalidation. They have no UpdatedAt until
+// they are flushed: versions are assigned by the database, and a version
+// from the clock of the instance could be mistaken for a later one.
+type WriteBehind struct {
+	units.Units
+	store  Store
+	writer units.BatchWriter
+	// layers are in-memory layers over the write-behind that units dropped
+	// on flush are evicted from, see EvictFrom.
+	layers []units.Layer
+
+	batchSize  int
+	interval   time.Duration
+	retries    int
+	minBackoff time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*write
+	// flushMu serializes flushes and deletes
+	flushMu sync.Mutex
+	flushC  chan struct{}
+	closed  chan struct{}
+	stopped chan struct{}
+}
+
+// write is a pending write of a unit, create is true when
+// the unit may not exist in the database yet.
+type write struct {
+	unit   *models.Unit
+	create bool
+}
+
+type Option func(*WriteBehind)
+
+// WithBatch sets the max number of units written at once and how often
+// pending writes are flushed. A flush starts earlier when size writes are pending.
+func WithBatch(size int, interval time.Duration) Option {
+	return func(w *WriteBehind) {
+		w.batchSize = size
+		w.interval = interval
+	}
+}
+
+// WithRetries sets how many times a failed batch is retried during a flush,
+// the delay between attempts doubles starting from minBackoff. Batches that
+// still fail are kept and retried on the next flush.
+func WithRetries(retries int, minBackoff time.Duration) Option {
+	return func(w *WriteBehind) {
+		w.retries = retries
+		w.minBackoff = minBackoff
+	}
+}
+
+// NewWriteBehind returns a layer over store that writes units with writer,
+// writer is usually the next layer of store.
+func NewWriteBehind(store Store, writer units.BatchWriter, opts ...Option) *WriteBehind {
+	w := &WriteBehind{
+		Units:      store,
+		store:      store,
+		writer:     writer,
+		batchSize:  defaultBatchSize,
+		interval:   defaultInterval,
+		retries:    defaultRetries,
+		minBackoff: defaultMinBackoff,
+		pending:    make(map[string]*write),
+		flushC:     make(chan struct{}, 1),
+		closed:     make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// EvictFrom sets in-memory layers over the write-behind, ordered from the
+// outermost one, units dropped on flush are evicted from them as well as
+// from the store. It must be called before writes are made.
+func (w *WriteBehind) EvictFrom(layers ...units.Layer) {
+	w.layers = layers
+}
+
+// Create keeps existing units as is, like the database does. Only pending
+// and stored units are known to exist without a database round trip, others
+// are created and dropped on flush if the database has them, see evict.
+func (w *WriteBehind) Create(ctx context.Context, unit *models.Unit) error {
+	if w.IsPending(unit.ID) || w.store.Contains(unit.ID) {
+		return nil
+	}
+
+	unit.UpdatedAt = time.Time{}
+	w.enqueue(unit, true)
+	return nil
+}
+
+func (w *WriteBehind) Update(ctx context.Context, id string, data []byte) (*models.Unit, error) {
+	var createdAt time.Time
+	w.mu.Lock()
+	p, ok := w.pending[id]
+	if ok {
+		createdAt = p.unit.CreatedAt
+	}
+	w.mu.Unlock()
+
+	if !ok {
+		unit, err := w.store.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		createdAt = unit.CreatedAt
+	}
+
+	unit := &models.Unit{
+		ID:        id,
+		Data:      data,
+		CreatedAt: createdAt,
+	}
+	w.enqueue(unit, false)
+	return unit, nil
+}
+
+// FindByID returns the pending write of the unit if there is one.
+func (w *WriteBehind) FindByID(ctx context.Context, id string) (*models.Unit, error) {
+	if pending := w.pendingUnits([]string{id}); len(pending) > 0 {
+		return pending[0], nil
+	}
+	return w.store.FindByID(ctx, id)
+}
+
+// FindByIDs returns pending writes of units and looks up the rest in the store.
+func (w *WriteBehind) FindByIDs(ctx context.Context, ids []string) (models.Units, error) {
+	return units.FindByIDs(ctx, ids, w.pendingUnits, func(...*models.Unit) {}, w.store, nil)
+}
+
+// Delete drops a pending write of the unit and deletes it from the store
+// and the database. Units that were never flushed are only dropped.
+func (w *WriteBehind) Delete(ctx context.Context, id string) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	p, ok := w.pending[id]
+	delete(w.pending, id)
+	w.mu.Unlock()
+
+	err := w.store.Delete(ctx, id)
+	if errors.Is(err, units.ErrNotFound) && ok && p.create {
+		w.store.Invalidate(id)
+		return nil
+	}
+	return err
+}
+
+// FetchAll flushes pending writes first, so units created but not flushed
+// are not removed by the sync as missing from the database.
+func (w *WriteBehind) FetchAll(ctx context.Context) (models.Units, error) {
+	if err := w.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return w.store.FetchAll(ctx)
+}
+
+// Scan flushes pending writes first like FetchAll.
+func (w *WriteBehind) Scan(ctx context.Context, fn func(batch models.Units) error) error {
+	if err := w.Flush(ctx); err != nil {
+		return err
+	}
+	return units.Scan(ctx, w.store, fn)
+}
+
+// FetchChanges flushes pending writes first,
+// so tombstones of recreated units don't remove them.
+func (w *WriteBehind) FetchChanges(ctx context.Context, since time.Time) (*models.Changes, error) {
+	if err := w.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return w.store.FetchChanges(ctx, since)
+}
+
+// Pending returns the number of writes not flushed yet.
+func (w *WriteBehind) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+// Run flushes pending writes every interval and when a batch is full
+// until Close is called.
+func (w *WriteBehind) Run(ctx context.Context) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.closed:
+			return
+		case <-ticker.C:
+		case <-w.flushC:
+		}
+		if err := w.Flush(ctx); err != nil {
+			log.Error().Err(err).Int("pending", w.Pending()).Msg("flush pending writes")
+		}
+	}
+}
+
+// Close stops Run and flushes all pending writes,
+// ctx bounds the time given to the final flush.
+func (w *WriteBehind) Close(ctx context.Context) error {
+	close(w.closed)
+	select {
+	case <-w.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return w.Flush(ctx)
+}
+
+// Flush writes writes pending at the moment of the call in batches.
+// Failed batches are kept pending and the first error is returned.
+func (w *WriteBehind) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	for left := w.Pending(); left > 0; {
+		batch := w.take(w.batchSize)
+		if len(batch) == 0 {
+			return nil
+		}
+		left -= len(batch)
+
+		if err := w.write(ctx, batch); err != nil {
+			w.requeue(batch)
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WriteBehind) write(ctx context.Context, batch []*write) error {
+	// the writer sets versions of the units, pending units are not changed
+	// since they may be read concurrently
+	created := make(models.Units, 0, len(batch))
+	updated := make(models.Units, 0, len(batch))
+	for _, p := range batch {
+		unit := *p.unit
+		if p.create {
+			created = append(created, &unit)
+		} else {
+			updated = append(updated, &unit)
+		}
+	}
+
+	backoff := w.minBackoff
+	for attempt := 0; ; attempt++ {
+		notFound, existing, err := w.writer.WriteBatch(ctx, created, updated)
+		if err == nil {
+			if len(notFound) > 0 {
+				// deleted by another instance after the update was acknowledged
+				log.Warn().Strs("ids", notFound).Msg("updated units not found on flush")
+			}
+			if len(existing) > 0 {
+				// created by another instance after the create was acknowledged
+				log.Warn().Strs("ids", existing).Msg("created units existed on flush, the writes are dropped")
+			}
+			dropped := append(append(make([]string, 0, len(notFound)+len(existing)), notFound...), existing...)
+			w.evict(dropped...)
+			w.saveVersions(append(created, updated...), dropped)
+			return nil
+		}
+		if attempt >= w.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// saveVersions stores written units with the versions assigned by
+// the database. Units written again since keep their pending writes, the lock
+// is held, so a write can't be put to the store in between.
+func (w *WriteBehind) saveVersions(written models.Units, dropped []string) {
+	skip := make(map[string]struct{}, len(dropped))
+	for _, id := range dropped {
+		skip[id] = struct{}{}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	flushed := make(models.Units, 0, len(written))
+	for _, unit := range written {
+		if _, ok := skip[unit.ID]; ok {
+			continue
+		}
+		if _, ok := w.pending[unit.ID]; !ok {
+			flushed = append(flushed, unit)
+		}
+	}
+	w.store.Save(flushed...)
+}
+
+// evict removes units dropped on flush from the store and the layers over it,
+// starting from the innermost one. Units written again since are kept.
+func (w *WriteBehind) evict(ids ...string) {
+	ids = w.notPending(ids)
+	if len(ids) == 0 {
+		return
+	}
+	w.store.Invalidate(ids...)
+	for i := len(w.layers) - 1; i >= 0; i-- {
+		w.layers[i].Invalidate(ids...)
+	}
+}
+
+func (w *WriteBehind) enqueue(unit *models.Unit, create bool) {
+	w.mu.Lock()
+	if p, ok := w.pending[unit.ID]; ok {
+		p.unit = unit
+		p.create = p.create || create
+	} else {
+		w.pending[unit.ID] = &write{unit: unit, create: create}
+	}
+	// the store is written under the lock, so it keeps the last pending write
+	w.store.Put(unit)
+	full := len(w.pending) >= w.batchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.flushC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take removes up to n pending writes.
+func (w *WriteBehind) take(n int) []*write {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := make([]*write, 0, n)
+	for id, p := range w.pending {
+		if len(batch) == n {
+			break
+		}
+		batch = append(batch, p)
+		delete(w.pending, id)
+	}
+	return batch
+}
+
+// requeue returns writes of a failed batch, writes made since
+// the batch was taken are newer and are kept.
+func (w *WriteBehind) requeue(batch []*write) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range batch {
+		if newer, ok := w.pending[p.unit.ID]; ok {
+			newer.create = newer.create || p.create
+		} else {
+			w.pending[p.unit.ID] = p
+		}
+	}
+}
+
+// IsPending reports whether a write of the unit is not flushed yet.
+func (w *WriteBehind) IsPending(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.pending[id]
+	return ok
+}
+
+// notPending returns ids of units without pending writes.
+func (w *WriteBehind) notPending(ids []string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	left := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := w.pending[id]; !ok {
+			left = append(left, id)
+		}
+	}
+	return left
+}
+
+func (w *WriteBehind) pendingUnits(ids []string) []*models.Unit {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	units := make([]*models.Unit, 0)
+	for _, id := range ids {
+		if p, ok := w.pending[id]; ok {
+			units = append(units, p.unit)
+		}
+	}
+	return units
+}
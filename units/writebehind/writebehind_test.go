@@ -0,0 +1,314 @@
+package writebehind
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AltMax/art-test/models"
+	"github.com/AltMax/art-test/units"
+	"github.com/AltMax/art-test/units/mocks"
+	"github.com/AltMax/art-test/units/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type batch struct {
+	created models.Units
+	updated models.Units
+}
+
+// batchWriter records batches, it fails while err is set.
+// Written units get version as UpdatedAt when it's set.
+type batchWriter struct {
+	mu       sync.Mutex
+	batches  []batch
+	notFound []string
+	existing []string
+	version  time.Time
+	err      error
+}
+
+func (b *batchWriter) WriteBatch(ctx context.Context, created, updated models.Units) ([]string, []string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return nil, nil, b.err
+	}
+	b.batches = append(b.batches, batch{created: created, updated: updated})
+	if !b.version.IsZero() {
+		for _, unit := range append(created, updated...) {
+			unit.UpdatedAt = b.version
+		}
+	}
+	return b.notFound, b.existing, nil
+}
+
+func (b *batchWriter) written() []batch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]batch(nil), b.batches...)
+}
+
+func newTestWriteBehind(opts ...Option) (*WriteBehind, *mocks.Units, *batchWriter) {
+	unitsMock := &mocks.Units{}
+	writer := &batchWriter{}
+	opts = append([]Option{WithRetries(0, time.Millisecond)}, opts...)
+	return NewWriteBehind(store.NewStore(unitsMock), writer, opts...), unitsMock, writer
+}
+
+func Test_Update_Coalesced(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind()
+	ctx := context.Background()
+
+	unit := randomUnit()
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+
+	for _, data := range []string{"1", "2", "3"} {
+		updated, err := w.Update(ctx, unit.ID, []byte(data))
+		require.NoError(t, err)
+		require.Equal(t, unit.CreatedAt, updated.CreatedAt)
+	}
+	require.Equal(t, 1, w.Pending())
+	require.Empty(t, writer.written())
+
+	// acknowledged writes are read before they are flushed
+	stored, err := w.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("3"), stored.Data)
+
+	err = w.Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, w.Pending())
+
+	batches := writer.written()
+	require.Len(t, batches, 1)
+	require.Empty(t, batches[0].created)
+	require.Len(t, batches[0].updated, 1)
+	require.Equal(t, []byte("3"), batches[0].updated[0].Data)
+	unitsMock.AssertExpectations(t)
+}
+
+func Test_Update_NotFound(t *testing.T) {
+	w, unitsMock, _ := newTestWriteBehind()
+
+	unitsMock.On("FindByID", mock.Anything, "unknown").Return(nil, units.ErrNotFound)
+	_, err := w.Update(context.Background(), "unknown", []byte("data"))
+	require.ErrorIs(t, err, units.ErrNotFound)
+	require.Equal(t, 0, w.Pending())
+}
+
+func Test_Create(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind()
+	ctx := context.Background()
+
+	// the database is not read to find out whether the unit exists
+	unit := randomUnit()
+	err := w.Create(ctx, unit)
+	require.NoError(t, err)
+
+	// an update of a pending create is still a create
+	_, err = w.Update(ctx, unit.ID, []byte("updated"))
+	require.NoError(t, err)
+	// a pending unit exists already
+	err = w.Create(ctx, randomUnitWithID(unit.ID))
+	require.NoError(t, err)
+
+	err = w.Flush(ctx)
+	require.NoError(t, err)
+	batches := writer.written()
+	require.Len(t, batches, 1)
+	require.Empty(t, batches[0].updated)
+	require.Len(t, batches[0].created, 1)
+	require.Equal(t, []byte("updated"), batches[0].created[0].Data)
+	unitsMock.AssertExpectations(t)
+}
+
+func Test_Create_Stored(t *testing.T) {
+	w, unitsMock, _ := newTestWriteBehind()
+	ctx := context.Background()
+
+	unit := randomUnit()
+	w.store.Save(unit)
+	err := w.Create(ctx, randomUnitWithID(unit.ID))
+	require.NoError(t, err)
+	require.Equal(t, 0, w.Pending())
+
+	actualUnit, err := w.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, unit.Data, actualUnit.Data)
+	unitsMock.AssertExpectations(t)
+}
+
+func Test_Flush_Retry(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind()
+	ctx := context.Background()
+
+	unit := randomUnit()
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+	_, err := w.Update(ctx, unit.ID, []byte("1"))
+	require.NoError(t, err)
+
+	writer.err = errors.New("database is down")
+	err = w.Flush(ctx)
+	require.Error(t, err)
+	require.Equal(t, 1, w.Pending())
+
+	// a write made after the failure wins over the failed one
+	_, err = w.Update(ctx, unit.ID, []byte("2"))
+	require.NoError(t, err)
+
+	writer.err = nil
+	err = w.Flush(ctx)
+	require.NoError(t, err)
+	batches := writer.written()
+	require.Len(t, batches, 1)
+	require.Equal(t, []byte("2"), batches[0].updated[0].Data)
+}
+
+func Test_Flush_Versions(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind()
+	ctx := context.Background()
+
+	unit := randomUnit()
+	unit.UpdatedAt = time.Now().UTC()
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+	updated, err := w.Update(ctx, unit.ID, []byte("updated"))
+	require.NoError(t, err)
+	// the version is assigned by the database on flush
+	require.True(t, updated.UpdatedAt.IsZero())
+
+	// pending writes are read even if the store dropped them
+	// or a sync stored the version from the database again
+	w.store.Invalidate(unit.ID)
+	actualUnit, err := w.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, updated, actualUnit)
+	w.store.Save(unit)
+	actualUnit, err = w.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, updated, actualUnit)
+
+	// the stored unit gets the version assigned by the database,
+	// the acknowledged unit is not changed
+	writer.version = unit.UpdatedAt.Add(time.Second)
+	err = w.Flush(ctx)
+	require.NoError(t, err)
+	actualUnit, err = w.FindByID(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, writer.version, actualUnit.UpdatedAt)
+	require.Equal(t, []byte("updated"), actualUnit.Data)
+	require.True(t, updated.UpdatedAt.IsZero())
+}
+
+// layer records invalidated ids.
+type layer struct {
+	units.Layer
+	invalidated []string
+}
+
+func (l *layer) Invalidate(ids ...string) {
+	l.invalidated = append(l.invalidated, ids...)
+}
+
+func Test_Flush_Dropped(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind()
+	outer := &layer{}
+	w.EvictFrom(outer)
+	ctx := context.Background()
+
+	created, updated := randomUnit(), randomUnit()
+	require.NoError(t, w.Create(ctx, created))
+	unitsMock.On("FindByID", mock.Anything, updated.ID).Return(updated, nil).Once()
+	_, err := w.Update(ctx, updated.ID, []byte("updated"))
+	require.NoError(t, err)
+
+	// the unit was created and the updated one deleted by another instance,
+	// both are evicted, so they are read from the database again
+	writer.existing = []string{created.ID}
+	writer.notFound = []string{updated.ID}
+	err = w.Flush(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{created.ID, updated.ID}, outer.invalidated)
+
+	existing := randomUnitWithID(created.ID)
+	unitsMock.On("FindByID", mock.Anything, created.ID).Return(existing, nil).Once()
+	actualUnit, err := w.FindByID(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, existing, actualUnit)
+	unitsMock.On("FindByID", mock.Anything, updated.ID).Return(nil, units.ErrNotFound).Once()
+	_, err = w.FindByID(ctx, updated.ID)
+	require.ErrorIs(t, err, units.ErrNotFound)
+	unitsMock.AssertExpectations(t)
+}
+
+func Test_Run_BatchSize(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind(WithBatch(2, time.Hour))
+	ctx := context.Background()
+	go w.Run(ctx)
+
+	for i := 0; i < 3; i++ {
+		unit := randomUnit()
+		unitsMock.On("FindByID", mock.Anything, unit.ID).Return(unit, nil).Once()
+		_, err := w.Update(ctx, unit.ID, []byte("updated"))
+		require.NoError(t, err)
+	}
+
+	// the full batch is flushed without waiting for the interval
+	require.Eventually(t, func() bool {
+		return len(writer.written()) > 0
+	}, time.Second, time.Millisecond)
+
+	// the rest is flushed on close
+	err := w.Close(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, w.Pending())
+
+	written := 0
+	for _, b := range writer.written() {
+		require.LessOrEqual(t, len(b.updated), 2)
+		written += len(b.updated)
+	}
+	require.Equal(t, 3, written)
+}
+
+func Test_Delete_Pending(t *testing.T) {
+	w, unitsMock, writer := newTestWriteBehind()
+	ctx := context.Background()
+
+	unit := randomUnit()
+	err := w.Create(ctx, unit)
+	require.NoError(t, err)
+
+	unitsMock.On("Delete", mock.Anything, unit.ID).Return(units.ErrNotFound).Once()
+	err = w.Delete(ctx, unit.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, w.Pending())
+
+	unitsMock.On("FindByID", mock.Anything, unit.ID).Return(nil, units.ErrNotFound).Once()
+	_, err = w.FindByID(ctx, unit.ID)
+	require.ErrorIs(t, err, units.ErrNotFound)
+
+	err = w.Flush(ctx)
+	require.NoError(t, err)
+	require.Empty(t, writer.written())
+	unitsMock.AssertExpectations(t)
+}
+
+func randomUnit() *models.Unit {
+	return randomUnitWithID(uuid.New().String())
+}
+
+func randomUnitWithID(id string) *models.Unit {
+	buf := make([]byte, 50)
+	rand.Read(buf)
+	return &models.Unit{
+		ID:        id,
+		Data:      buf,
+		CreatedAt: time.Now().UTC(),
+	}
+}